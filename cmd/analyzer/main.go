@@ -2,50 +2,190 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"syscall"
 	"time"
 
+	"home24/internal/analyzer"
+	"home24/internal/auth"
 	"home24/internal/config"
 	"home24/internal/handlers"
+	"home24/internal/jobs"
+	"home24/internal/metrics"
+	"home24/internal/ratelimit"
+	"home24/internal/rpc"
+	"home24/internal/store"
+	"home24/internal/tracing"
+	"home24/internal/version"
+	"home24/internal/webhook"
 	"home24/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
-	log := logger.New()
+	os.Exit(run())
+}
+
+// run starts the servers and blocks until they have shut down. It returns
+// the exit code rather than exiting, so the deferred cleanup of the store,
+// the logs and the traces always runs.
+func run() int {
+	// Parse the command line before anything is logged so -h stays readable
+	loader, err := config.NewLoader(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "analyzer: %v\n", err)
+		return 2
+	}
+
+	// Load configuration from the file, environment and flags
 	fmt.Println("Web Page Analyzer - Starting...")
-	log.Info("starting web page analyzer application")
+	cfg, err := loader.Load()
+	if err != nil {
+		bootstrap, _ := logger.New(logger.DefaultConfig())
+		bootstrap.Error("failed to load configuration", slog.String("path", loader.Path), slog.String("error", err.Error()))
+		return 1
+	}
 
-	// Load configuration
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config/application.yaml"
+	logs, err := logger.New(cfg.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging: %v\n", err)
+		return 1
 	}
+	defer logs.Close()
+	log := logs.Logger
+	// The analyzer logs through the default logger
+	slog.SetDefault(log)
 
-	cfg, err := config.LoadConfig(configPath)
+	build := version.Get()
+	log.Info("starting web page analyzer application",
+		slog.String("version", build.Version),
+		slog.String("commit", build.Commit),
+		slog.String("go_version", build.GoVersion))
+
+	// Export traces and accept the trace context of callers
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, build.Version)
 	if err != nil {
-		log.Error("failed to load configuration", slog.String("error", err.Error()))
-		os.Exit(1)
+		log.Error("failed to set up tracing", slog.String("error", err.Error()))
+		return 1
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	// Register the metrics with the configured prefix; when they are
+	// disabled they are still recorded but not served
+	var registerer prometheus.Registerer
+	var gatherer prometheus.Gatherer
+	if cfg.Analyzer.EnableMetrics {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		registerer, gatherer = registry, registry
+	}
+	appMetrics := metrics.New(registerer, cfg.Analyzer.MetricsPrefix)
+	appMetrics.BuildInfo.WithLabelValues(build.Version, build.Commit, build.GoVersion).Set(1)
+
+	// Create the analyzer shared by the HTTP and gRPC servers
+	analyzerConfig := cfg.Analyzer
+	pageAnalyzer := analyzer.NewDefaultPageAnalyzer(&analyzerConfig, appMetrics)
+
+	// Reload the analyzer settings and the log level when the config file
+	// changes or on SIGHUP. A level set through the admin endpoint stays
+	// until the level in the file changes. Changes that need a restart are
+	// reported once, against the configuration applied last.
+	applied := cfg
+	watcher := config.NewWatcher(loader, cfg.Server.ConfigReloadInterval, func(next *config.Config) error {
+		if err := pageAnalyzer.Reload(next.Analyzer); err != nil {
+			return err
+		}
+		if next.Logging.Level != applied.Logging.Level {
+			level, _ := logger.ParseLevel(next.Logging.Level)
+			logs.SetLevel(level)
+		}
+		if needsRestart(applied, next) {
+			log.Warn("only analyzer settings are reloaded, restart to apply the other changes")
+		}
+		applied = next
+		return nil
+	}, log)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go watcher.Run(watchCtx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Info("received SIGHUP, reloading configuration")
+			watcher.Trigger()
+		}
+	}()
+
+	// Open the store that keeps the history of analyses
+	results, err := store.Open(cfg.History)
+	if err != nil {
+		log.Error("failed to open history store", slog.String("error", err.Error()))
+		return 1
+	}
+	defer results.Close()
+
+	// Require API keys, either configured or stored next to the history
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		keys, _ := results.(auth.KeyStore)
+		authenticator = auth.NewAuthenticator(cfg.Auth, keys)
+		if existing, err := authenticator.ListKeys(context.Background()); err == nil && len(existing) == 0 {
+			log.Warn("authentication is enabled but there are no API keys, every request will be rejected")
+		}
+	}
+
+	// Limit the analyses each client can start
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter = ratelimit.NewLimiter(cfg.RateLimit, appMetrics)
 	}
 
-	// Create server
-	router := handlers.NewRouter(log)
+	// Run asynchronous jobs and resume the ones saved at the last shutdown
+	// once the router has registered their kinds
+	jobManager := jobs.NewManager(cfg.Jobs, log)
+	router := handlers.NewRouter(logs, pageAnalyzer, results, jobManager, cfg.Batch, cfg.Sitemap, webhook.NewNotifier(cfg.Webhooks, appMetrics, log), authenticator, limiter, gatherer)
+	if _, err := jobManager.Resume(context.Background(), authenticator); err != nil {
+		log.Error("failed to resume jobs", slog.String("path", cfg.Jobs.CheckpointFile), slog.String("error", err.Error()))
+	}
+
+	// Create server. Requests that are still running when the shutdown
+	// timeout has passed are canceled through this context.
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
-		ReadTimeout:  parseDuration(cfg.Server.ReadTimeout),
-		WriteTimeout: parseDuration(cfg.Server.WriteTimeout),
-		IdleTimeout:  parseDuration(cfg.Server.IdleTimeout),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return requestCtx },
 	}
 
 	fmt.Printf("Server starting on port %s...\n", cfg.Server.Port)
 	log.Info("server starting", slog.String("port", cfg.Server.Port))
 	// Error channel for server errors
-	serverErrors := make(chan error, 1)
+	serverErrors := make(chan error, 3)
 
 	go func() {
 		log.Info("starting server", slog.String("port", cfg.Server.Port))
@@ -55,6 +195,41 @@ func main() {
 		}
 	}()
 
+	// Start the gRPC server next to the HTTP server
+	grpcServer := rpc.NewGRPCServer(store.NewRecorder(pageAnalyzer, results, pageAnalyzer.Config, log), authenticator, limiter, log)
+	go func() {
+		log.Info("starting grpc server", slog.String("port", cfg.Server.GRPCPort))
+		listener, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+		if err != nil {
+			serverErrors <- err
+			return
+		}
+		err = grpcServer.Serve(listener)
+		if err != nil {
+			serverErrors <- err
+		}
+	}()
+
+	// Serve the profiler and debug endpoints on their own listener, if
+	// enabled. It has no write timeout, as CPU profiles and traces are
+	// recorded for as long as the caller asks.
+	var adminSrv *http.Server
+	if cfg.Server.AdminAddress != "" {
+		adminSrv = &http.Server{
+			Addr:        cfg.Server.AdminAddress,
+			Handler:     handlers.NewAdminRouter(logs, pageAnalyzer, authenticator),
+			ReadTimeout: cfg.Server.ReadTimeout,
+			IdleTimeout: cfg.Server.IdleTimeout,
+		}
+		go func() {
+			log.Info("starting admin server", slog.String("address", cfg.Server.AdminAddress))
+			err := adminSrv.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErrors <- err
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -63,32 +238,80 @@ func main() {
 	select {
 	case err := <-serverErrors:
 		log.Error("server error", slog.String("error", err.Error()))
-		os.Exit(1)
+		return 1
 	case <-quit:
-		log.Info("shutting down server...")
+		log.Info("shutting down server...", slog.Duration("timeout", cfg.Server.ShutdownTimeout))
 
-		// Create context with timeout for shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// Running requests, calls and jobs may finish until the timeout
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
-		// Try to gracefully shutdown the server
+		// Stop accepting gRPC calls and let running ones finish
+		grpcStopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+
+		// Stop accepting jobs and let running ones finish; the queued ones
+		// and those that don't finish in time are saved to be resumed
+		jobsClosed := make(chan error, 1)
+		go func() {
+			jobsClosed <- jobManager.Close(ctx)
+		}()
+
+		// Let running requests finish, then cancel the rest so their
+		// analyses stop and save what they have found so far
 		err := srv.Shutdown(ctx)
+		if err != nil {
+			log.Warn("canceling running requests", slog.String("reason", err.Error()))
+			cancelRequests()
+			canceledCtx, cancelWait := context.WithTimeout(context.Background(), 5*time.Second)
+			err = srv.Shutdown(canceledCtx)
+			cancelWait()
+		}
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		if jobsErr := <-jobsClosed; jobsErr != nil {
+			log.Warn("jobs did not finish before the shutdown timeout", slog.String("error", jobsErr.Error()))
+		}
+
+		// The admin server stays up until everything else has stopped, so
+		// a slow shutdown can be looked into
+		if adminSrv != nil {
+			adminSrv.Close()
+		}
 		if err != nil {
 			log.Error("server forced to shutdown", slog.String("error", err.Error()))
-			os.Exit(1)
+			return 1
 		}
 	}
 
 	log.Info("server exited properly")
 	fmt.Println("Server shutdown complete")
+	return 0
 }
 
-// parseDuration parses a duration string (e.g., "10s", "30s", "120s")
-func parseDuration(s string) time.Duration {
-	d, err := time.ParseDuration(s)
-	if err != nil {
-		// Return a default duration if parsing fails
-		return 10 * time.Second
-	}
-	return d
+// needsRestart reports whether settings other than the analyzer's and the
+// log level changed, which only take effect after a restart. The metrics
+// settings of the analyzer are among them.
+func needsRestart(current, next *config.Config) bool {
+	currentLogging, nextLogging := current.Logging, next.Logging
+	currentLogging.Level, nextLogging.Level = "", ""
+
+	return current.Analyzer.EnableMetrics != next.Analyzer.EnableMetrics ||
+		!reflect.DeepEqual(currentLogging, nextLogging) ||
+		current.Analyzer.MetricsPrefix != next.Analyzer.MetricsPrefix ||
+		!reflect.DeepEqual(current.Server, next.Server) ||
+		!reflect.DeepEqual(current.Jobs, next.Jobs) ||
+		!reflect.DeepEqual(current.Batch, next.Batch) ||
+		!reflect.DeepEqual(current.Sitemap, next.Sitemap) ||
+		!reflect.DeepEqual(current.Webhooks, next.Webhooks) ||
+		!reflect.DeepEqual(current.History, next.History) ||
+		!reflect.DeepEqual(current.Tracing, next.Tracing) ||
+		!reflect.DeepEqual(current.RateLimit, next.RateLimit) ||
+		!reflect.DeepEqual(current.Auth, next.Auth)
 }
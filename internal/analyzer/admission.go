@@ -0,0 +1,225 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AdmissionConfig limits the work of the whole process, across all
+// analyses, so a traffic spike queues up instead of exhausting file
+// descriptors. A limit of 0 turns that limit off.
+type AdmissionConfig struct {
+	// MaxConcurrentAnalyses is the number of analyses that run at once.
+	// Further analyses wait in the queue.
+	MaxConcurrentAnalyses int `yaml:"maxConcurrentAnalyses"`
+
+	// MaxQueuedAnalyses is the number of analyses that may wait. Analyses
+	// beyond it fail with OVERLOADED right away.
+	MaxQueuedAnalyses int `yaml:"maxQueuedAnalyses"`
+
+	// QueueTimeout is how long an analysis waits in the queue before it
+	// fails with OVERLOADED
+	QueueTimeout time.Duration `yaml:"queueTimeout"`
+
+	// MaxOutboundRequests is the number of requests to analyzed sites in
+	// flight at once, and so the number of connections in use. Requests
+	// over it wait for a free slot.
+	MaxOutboundRequests int `yaml:"maxOutboundRequests"`
+}
+
+// Validate checks the limits
+func (c AdmissionConfig) Validate() error {
+	var errs []error
+	if c.MaxConcurrentAnalyses < 0 {
+		errs = append(errs, fmt.Errorf("maxConcurrentAnalyses: must not be negative, got %d", c.MaxConcurrentAnalyses))
+	}
+	if c.MaxQueuedAnalyses < 0 {
+		errs = append(errs, fmt.Errorf("maxQueuedAnalyses: must not be negative, got %d", c.MaxQueuedAnalyses))
+	}
+	if c.MaxConcurrentAnalyses > 0 && c.QueueTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("queueTimeout: must be positive, got %s", c.QueueTimeout))
+	}
+	if c.MaxOutboundRequests < 0 {
+		errs = append(errs, fmt.Errorf("maxOutboundRequests: must not be negative, got %d", c.MaxOutboundRequests))
+	}
+	return errors.Join(errs...)
+}
+
+// Reasons an analysis is not admitted
+var (
+	errQueueFull    = errors.New("too many analyses are waiting")
+	errQueueTimeout = errors.New("timed out waiting for a free slot")
+)
+
+// semaphore admits a limited number of holders at once. Callers over the
+// limit wait in a first-in, first-out queue, which may be bounded. The
+// limits can be changed while it is in use.
+type semaphore struct {
+	mu       sync.Mutex
+	limit    int
+	maxQueue int
+	active   int
+	queue    []chan struct{}
+
+	// inFlight and queued follow the number of holders and waiters
+	inFlight prometheus.Gauge
+	queued   prometheus.Gauge
+}
+
+// newSemaphore creates a semaphore for limit holders; a limit of 0 admits
+// everyone. A negative maxQueue doesn't bound the queue.
+func newSemaphore(limit, maxQueue int, inFlight, queued prometheus.Gauge) *semaphore {
+	return &semaphore{limit: limit, maxQueue: maxQueue, inFlight: inFlight, queued: queued}
+}
+
+// resize changes the limits. Waiters are admitted if the limit grew; if it
+// shrank, the holders above it finish first.
+func (s *semaphore) resize(limit, maxQueue int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	s.maxQueue = maxQueue
+	s.admitWaiters()
+}
+
+// acquire waits until the caller is admitted, the queue timeout passes or
+// the context is done. A timeout of 0 waits as long as the context allows.
+// The returned function releases the slot and must be called once.
+func (s *semaphore) acquire(ctx context.Context, timeout time.Duration) (func(), error) {
+	s.mu.Lock()
+	if s.limit == 0 || (s.active < s.limit && len(s.queue) == 0) {
+		s.active++
+		s.update()
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	if s.maxQueue >= 0 && len(s.queue) >= s.maxQueue {
+		s.mu.Unlock()
+		return nil, errQueueFull
+	}
+	ready := make(chan struct{})
+	s.queue = append(s.queue, ready)
+	s.update()
+	s.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-expired:
+		err = errQueueTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, waiter := range s.queue {
+		if waiter == ready {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			s.update()
+			return nil, err
+		}
+	}
+	// The slot was handed over while giving up, so pass it on
+	s.active--
+	s.admitWaiters()
+	return nil, err
+}
+
+// release frees a slot and hands it to the longest waiting caller
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	s.admitWaiters()
+}
+
+// admitWaiters admits waiters while there are free slots. The lock must be
+// held.
+func (s *semaphore) admitWaiters() {
+	for len(s.queue) > 0 && (s.limit == 0 || s.active < s.limit) {
+		close(s.queue[0])
+		s.queue = s.queue[1:]
+		s.active++
+	}
+	s.update()
+}
+
+// state returns the limit and the number of holders and waiters
+func (s *semaphore) state() SlotState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SlotState{Limit: s.limit, InUse: s.active, Waiting: len(s.queue)}
+}
+
+// update sets the gauges. The lock must be held.
+func (s *semaphore) update() {
+	if s.inFlight != nil {
+		s.inFlight.Set(float64(s.active))
+	}
+	if s.queued != nil {
+		s.queued.Set(float64(len(s.queue)))
+	}
+}
+
+// limitedTransport holds a slot of the outbound semaphore for every request
+// until its response body is read or closed
+type limitedTransport struct {
+	base  http.RoundTripper
+	slots *semaphore
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.slots.acquire(req.Context(), 0)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the slot of a request once its body is read to the
+// end or closed, whichever happens first. Pages are read completely before
+// their links are checked, so they don't hold on to slots meanwhile.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Read implements io.Reader
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
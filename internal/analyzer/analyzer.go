@@ -1,50 +1,183 @@
 package analyzer
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"home24/internal/metrics"
+	"home24/pkg/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
 )
 
 // DefaultPageAnalyzer implements the PageAnalyzer interface
 type DefaultPageAnalyzer struct {
-	client  *http.Client
 	parser  HTMLParser
-	checker LinkChecker
-	metrics MetricsCollector
 	log     Logger
-	config  *AnalyzerConfig
+	metrics MetricsCollector
+	tracer  trace.Tracer
+
+	// analyses and outbound limit the analyses and the outbound requests of
+	// the whole process. They outlive reloads, which only resize them.
+	analyses *semaphore
+	outbound *semaphore
+
+	// conns counts the open outbound connections and inFlight holds the
+	// analyses that are waiting or running, for DebugState
+	conns    *connCounter
+	inFlight sync.Map
+
+	// settings holds everything built from the configuration. Reload
+	// replaces it as a whole; running analyses keep the settings they
+	// started with.
+	settings atomic.Pointer[settings]
+}
+
+// settings is the configuration of an analyzer together with the HTTP
+// client and link checker built from it
+type settings struct {
+	config    *AnalyzerConfig
+	client    *http.Client
+	transport *http.Transport
+	checker   LinkChecker
+
+	// initErr holds an invalid network configuration, which is reported by
+	// every Analyze call rather than silently ignored
+	initErr error
 }
 
-// NewDefaultPageAnalyzer creates a new DefaultPageAnalyzer
-func NewDefaultPageAnalyzer(config *AnalyzerConfig) *DefaultPageAnalyzer {
+// newSettings builds the client and link checker for a configuration. Every
+// request of the client takes a slot of the outbound semaphore and its
+// connections are counted by conns.
+func newSettings(config *AnalyzerConfig, log Logger, outbound *semaphore, conns *connCounter) *settings {
+	transport, initErr := newTransport(config.Network)
+	if initErr != nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.DialContext = conns.dialContext(transport.DialContext)
 	client := &http.Client{
-		Timeout: config.Timeout,
+		Timeout:   config.Timeout,
+		Transport: &limitedTransport{base: &proxyDialTransport{base: &credentialTransport{base: transport}}, slots: outbound},
+	}
+
+	return &settings{
+		config:    config,
+		client:    client,
+		transport: transport,
+		checker:   NewDefaultLinkChecker(client, log, config),
+		initErr:   initErr,
 	}
+}
 
+// NewDefaultPageAnalyzer creates a new DefaultPageAnalyzer recording to the
+// given metrics. With nil metrics nothing is recorded. Spans are created
+// with the global tracer provider installed at this point.
+func NewDefaultPageAnalyzer(config *AnalyzerConfig, m *metrics.Metrics) *DefaultPageAnalyzer {
+	if m == nil {
+		m = metrics.New(nil, "")
+	}
 	log := NewAnalyzerLogger(slog.Default())
-	parser := NewDefaultHTMLParser(log)
-	checker := NewDefaultLinkChecker(client, log, config)
-	metrics := NewPrometheusMetricsCollector(*config)
-
-	return &DefaultPageAnalyzer{
-		client:  client,
-		parser:  parser,
-		checker: checker,
-		metrics: metrics,
+	a := &DefaultPageAnalyzer{
+		parser:  NewDefaultHTMLParser(log),
 		log:     log,
-		config:  config,
+		metrics: NewPrometheusMetricsCollector(m),
+		tracer:  otel.Tracer(tracerName),
+		conns:   &connCounter{},
+	}
+	a.analyses = newSemaphore(config.Admission.MaxConcurrentAnalyses, config.Admission.MaxQueuedAnalyses, m.AnalysesInFlight, m.AnalysisQueueDepth)
+	a.outbound = newSemaphore(config.Admission.MaxOutboundRequests, -1, m.OutboundRequestsInFlight, nil)
+
+	s := newSettings(config, log, a.outbound, a.conns)
+	if s.initErr != nil {
+		slog.Default().Error("invalid analyzer network configuration", slog.String("error", s.initErr.Error()))
 	}
+	a.settings.Store(s)
+	return a
 }
 
-// Analyze performs a complete analysis of a webpage
+// Reload switches the analyzer to a new configuration. Analyses that are
+// already running finish with the old one. A configuration whose network
+// options are invalid is rejected and the current one stays active. The
+// metrics keep the registry and prefix they were created with. New limits
+// apply to running and waiting analyses as well.
+func (a *DefaultPageAnalyzer) Reload(config AnalyzerConfig) error {
+	s := newSettings(&config, a.log, a.outbound, a.conns)
+	if s.initErr != nil {
+		return NewAnalysisError(ErrInvalidConfig, "invalid network configuration", s.initErr)
+	}
+	a.analyses.resize(config.Admission.MaxConcurrentAnalyses, config.Admission.MaxQueuedAnalyses)
+	a.outbound.resize(config.Admission.MaxOutboundRequests, -1)
+	old := a.settings.Swap(s)
+	old.transport.CloseIdleConnections()
+	return nil
+}
+
+// Analyze performs a complete analysis of a webpage. Its log lines carry
+// the target URL along with the attributes already in the context. When
+// too many analyses are running it waits for a free slot, or fails with
+// OVERLOADED if the queue is full or the wait takes too long.
 func (a *DefaultPageAnalyzer) Analyze(ctx context.Context, targetURL string) (*AnalysisResult, error) {
+	ctx = logger.WithAttrs(ctx, slog.String("target_url", targetURL))
+	ctx, span := a.tracer.Start(ctx, "analyze", trace.WithAttributes(semconv.URLFull(targetURL)))
+	a.metrics.RecordRequest()
+	ctx, tracked, untrack := a.track(ctx, targetURL)
+	defer untrack()
+	result, err := a.admitAndAnalyze(ctx, targetURL, tracked)
+	if err != nil {
+		a.log.LogAnalysisError(ctx, err)
+		a.metrics.RecordError(err)
+		endSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(result.StatusCode),
+		attrLinks.Int(len(result.Links)),
+		attrAccessibleLinks.Int(result.AccessibleLinks),
+	)
+	span.End()
+	return result, nil
+}
+
+// admitAndAnalyze waits for a slot and runs the analysis in it
+func (a *DefaultPageAnalyzer) admitAndAnalyze(ctx context.Context, targetURL string, tracked *inFlight) (*AnalysisResult, error) {
+	queued := time.Now()
+	release, err := a.analyses.acquire(ctx, a.settings.Load().config.Admission.QueueTimeout)
+	if errors.Is(err, errQueueFull) || errors.Is(err, errQueueTimeout) {
+		return nil, NewAnalysisError(ErrOverloaded, "too many analyses are running", err)
+	}
+	if err != nil {
+		return nil, contextError(err, "stopped waiting for a free slot")
+	}
+	defer release()
+	tracked.admitted()
+	if wait := time.Since(queued); wait > time.Millisecond {
+		trace.SpanFromContext(ctx).AddEvent("admitted", trace.WithAttributes(attribute.Float64("wait_seconds", wait.Seconds())))
+	}
+	return a.analyze(ctx, targetURL, tracked)
+}
+
+// analyze runs the analysis for Analyze, which records its outcome
+func (a *DefaultPageAnalyzer) analyze(ctx context.Context, targetURL string, tracked *inFlight) (*AnalysisResult, error) {
 	startTime := time.Now()
-	a.log.LogAnalysisStart(targetURL)
+	a.log.LogAnalysisStart(ctx)
+
+	s := a.settings.Load()
+	if s.initErr != nil {
+		return nil, NewAnalysisError(ErrInvalidConfig, "invalid network configuration", s.initErr)
+	}
 
 	// Parse and validate URL
 	parsedURL, err := url.Parse(targetURL)
@@ -52,16 +185,42 @@ func (a *DefaultPageAnalyzer) Analyze(ctx context.Context, targetURL string) (*A
 		return nil, NewAnalysisError(ErrInvalidURL, "invalid URL", err)
 	}
 
+	// Bind the configured and per-analysis credentials to this analysis
+	ctx, closeSession, err := a.bindCredentials(ctx, s, parsedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSession()
+
 	// Fetch the page
-	resp, err := a.fetchPage(ctx, parsedURL)
+	fetchCtx, fetchSpan := a.tracer.Start(ctx, "fetch", trace.WithAttributes(semconv.URLFull(targetURL)))
+	resp, tracer, err := a.fetchPage(fetchCtx, s, parsedURL)
 	if err != nil {
+		endSpan(fetchSpan, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	// Read the whole body so the download phase is timed separately from parsing
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = NewAnalysisError(ErrFetchFailed, "failed to read response body", err)
+		endSpan(fetchSpan, err)
+		return nil, err
+	}
+	fetchSpan.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode), semconv.HTTPResponseBodySize(len(body)))
+	fetchSpan.End()
+	parseStart := time.Now()
+	timings := tracer.Timings(parseStart)
+	a.metrics.RecordPhaseTimings("page", timings)
+	a.metrics.RecordStage(StageFetch, parseStart.Sub(startTime))
+	emitEvent(ctx, Event{Type: EventFetched, URL: targetURL, StatusCode: resp.StatusCode})
+
 	// Parse HTML
-	doc, err := a.parser.ParseHTML(resp.Body)
+	_, parseSpan := a.tracer.Start(ctx, "parse")
+	doc, err := a.parser.ParseHTML(bytes.NewReader(body))
 	if err != nil {
+		endSpan(parseSpan, err)
 		return nil, err
 	}
 
@@ -69,24 +228,15 @@ func (a *DefaultPageAnalyzer) Analyze(ctx context.Context, targetURL string) (*A
 	title := a.parser.ExtractTitle(doc)
 	headings := a.parser.ExtractHeadings(doc)
 	links := a.parser.ExtractLinks(doc, parsedURL)
+	if links == nil {
+		links = []LinkInfo{}
+	}
 	forms := a.parser.ExtractForms(doc)
 	htmlVersion := a.parser.ExtractHTMLVersion(doc)
 
-	// Check links concurrently
-	linkResults := make(map[string]bool)
-	for _, link := range links {
-		isAccessible := a.checker.CheckAccessibility(ctx, link.URL)
-		linkResults[link.URL] = isAccessible
-		a.log.LogLinkCheck(link.URL, isAccessible)
-	}
-
-	// Count accessible links
-	accessibleLinks := 0
-	for _, isAccessible := range linkResults {
-		if isAccessible {
-			accessibleLinks++
-		}
-	}
+	// Redirects were followed, so relative URLs resolve against the final URL
+	finalURL := resp.Request.URL
+	canonical := a.parser.ExtractCanonical(doc, finalURL)
 
 	// Check for login form
 	hasLoginForm := false
@@ -99,58 +249,331 @@ func (a *DefaultPageAnalyzer) Analyze(ctx context.Context, targetURL string) (*A
 
 	// Create result
 	result := &AnalysisResult{
-		URL:             targetURL,
-		Title:           title,
-		Headings:        headings,
-		Links:           links,
-		AccessibleLinks: accessibleLinks,
-		HasLoginForm:    hasLoginForm,
-		HTMLVersion:     htmlVersion,
+		URL:          targetURL,
+		FinalURL:     finalURL.String(),
+		StatusCode:   resp.StatusCode,
+		Canonical:    canonical,
+		Title:        title,
+		Headings:     headings,
+		Links:        links,
+		HasLoginForm: hasLoginForm,
+		HTMLVersion:  htmlVersion,
+		Timings:      timings,
+	}
+	parseSpan.SetAttributes(
+		attrLinks.Int(len(links)),
+		attrHTMLVersion.String(htmlVersion),
+		attrLoginForm.Bool(hasLoginForm),
+	)
+	parseSpan.End()
+	checkStart := time.Now()
+	a.metrics.RecordStage(StageParse, checkStart.Sub(parseStart))
+	emitEvent(ctx, Event{Type: EventParsed, URL: targetURL, LinksTotal: len(links), Result: result.clone()})
+
+	// Check links concurrently
+	linkResults := a.checkLinks(ctx, s, parsedURL, links, tracked)
+	if err := ctx.Err(); err != nil {
+		analysisErr := contextError(err, "analysis stopped while checking links")
+		analysisErr.Partial = partialResult(result, linkResults, time.Since(startTime))
+		return nil, analysisErr
+	}
+	a.metrics.RecordStage(StageCheckLinks, time.Since(checkStart))
+
+	// Count accessible links
+	for _, isAccessible := range linkResults {
+		if isAccessible {
+			result.AccessibleLinks++
+		}
 	}
 
 	// Record metrics
-	duration := time.Since(startTime).Seconds()
+	result.Duration = time.Since(startTime)
+	duration := result.Duration.Seconds()
 	a.metrics.RecordDuration(duration)
 	a.metrics.RecordResults(result)
 
-	a.log.LogAnalysisComplete(targetURL, duration)
+	a.log.LogAnalysisComplete(ctx, duration)
+	emitEvent(ctx, Event{Type: EventDone, URL: targetURL, LinksChecked: len(links), LinksTotal: len(links), Result: result.clone()})
 	return result, nil
 }
 
-// fetchPage fetches the webpage with retry logic
-func (a *DefaultPageAnalyzer) fetchPage(ctx context.Context, url *url.URL) (*http.Response, error) {
+// partialResult returns a copy of the result with only the links that were
+// checked, for an analysis stopped while checking links
+func partialResult(result *AnalysisResult, checked map[string]bool, duration time.Duration) *AnalysisResult {
+	partial := result.clone()
+	partial.Partial = true
+	partial.Duration = duration
+	partial.Links = partial.Links[:0]
+	for _, link := range result.Links {
+		if accessible, ok := checked[link.URL]; ok {
+			partial.Links = append(partial.Links, link)
+			if accessible {
+				partial.AccessibleLinks++
+			}
+		}
+	}
+	return partial
+}
+
+// checkLinks checks the links with at most MaxConcurrentLinks requests in
+// flight, stores their status and timings and returns the accessibility by
+// URL. Relative links are resolved against the page URL before checking. The
+// use of the limit is recorded in tracked.
+func (a *DefaultPageAnalyzer) checkLinks(ctx context.Context, s *settings, pageURL *url.URL, links []LinkInfo, tracked *inFlight) map[string]bool {
+	targetURL := pageURL.String()
+	results := make(map[string]bool)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked := 0
+
+	limit := s.config.MaxConcurrentLinks
+	if limit < 1 {
+		limit = 1
+	}
+	semaphore := make(chan struct{}, limit)
+	tracked.checkingLinks(limit, len(links))
+
+	for i := range links {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return results
+		}
+		tracked.linkStarted()
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() {
+				tracked.linkDone()
+				<-semaphore
+			}()
+
+			linkURL := links[i].URL
+			if resolved, err := pageURL.Parse(linkURL); err == nil {
+				linkURL = resolved.String()
+			}
+			linkCtx, linkSpan := a.tracer.Start(ctx, "check_link", trace.WithAttributes(
+				semconv.URLFull(linkURL),
+				semconv.HTTPRequestMethodKey.String("HEAD"),
+			))
+			check := s.checker.CheckLink(linkCtx, linkURL)
+			endLinkSpan(linkSpan, check)
+			if !check.Accessible && ctx.Err() != nil {
+				// Stopped rather than checked, so it is left out of a partial result
+				return
+			}
+			a.metrics.RecordPhaseTimings("link", check.Timings)
+			a.metrics.RecordLinkCheck(check)
+			a.log.LogLinkCheck(ctx, links[i].URL, check.Accessible)
+
+			mu.Lock()
+			links[i].Accessible = check.Accessible
+			links[i].StatusCode = check.StatusCode
+			links[i].Timings = check.Timings
+			results[links[i].URL] = check.Accessible
+			checked++
+			event := Event{
+				Type:         EventLinkChecked,
+				URL:          targetURL,
+				Link:         links[i].URL,
+				Accessible:   check.Accessible,
+				StatusCode:   check.StatusCode,
+				LinksChecked: checked,
+				LinksTotal:   len(links),
+			}
+			mu.Unlock()
+
+			emitEvent(ctx, event)
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Get performs a single GET request with the analyzer's client, user agent,
+// network options and credentials. It is meant for auxiliary documents such
+// as sitemaps and robots.txt; the caller must close the response body.
+func (a *DefaultPageAnalyzer) Get(ctx context.Context, targetURL string) (*http.Response, error) {
+	s := a.settings.Load()
+	if s.initErr != nil {
+		return nil, NewAnalysisError(ErrInvalidConfig, "invalid network configuration", s.initErr)
+	}
+
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, NewAnalysisError(ErrInvalidURL, "invalid URL", err)
+	}
+
+	ctx, closeSession, err := a.bindCredentials(ctx, s, parsedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", parsedURL.String(), nil)
+	if err != nil {
+		closeSession()
+		return nil, NewAnalysisError(ErrFetchFailed, "failed to create request", err)
+	}
+	req.Header.Set("User-Agent", s.config.UserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		closeSession()
+		if ctx.Err() != nil || isTimeout(err) {
+			return nil, contextError(err, "stopped fetching "+targetURL)
+		}
+		if isBlocked(err) {
+			return nil, NewAnalysisError(ErrBlockedAddress, targetURL+" is on a blocked address", err)
+		}
+		return nil, NewAnalysisError(ErrFetchFailed, "failed to fetch "+targetURL, err)
+	}
+	resp.Body = &sessionBody{ReadCloser: resp.Body, closeSession: closeSession}
+	return resp, nil
+}
+
+// UserAgent returns the User-Agent the analyzer sends
+func (a *DefaultPageAnalyzer) UserAgent() string {
+	return a.settings.Load().config.UserAgent
+}
+
+// Config returns the configuration the analyzer runs with
+func (a *DefaultPageAnalyzer) Config() AnalyzerConfig {
+	return *a.settings.Load().config
+}
+
+// ConfigError returns the error that makes the configuration unusable, if
+// any. Analyses fail with INVALID_CONFIG while it is set.
+func (a *DefaultPageAnalyzer) ConfigError() error {
+	return a.settings.Load().initErr
+}
+
+// bindCredentials attaches a credential session for the configured and
+// per-analysis credentials to the context. The returned function releases
+// the session and must always be called.
+func (a *DefaultPageAnalyzer) bindCredentials(ctx context.Context, s *settings, target *url.URL) (context.Context, func(), error) {
+	requested, err := credentialsFromContext(ctx)
+	if err != nil {
+		return ctx, func() {}, NewAnalysisError(ErrInvalidCredentials, "invalid credentials", err)
+	}
+	creds := append(append([]Credentials{}, s.config.Credentials...), requested...)
+	if len(creds) == 0 {
+		return ctx, func() {}, nil
+	}
+	session, err := newCredentialSession(creds, target.Host, s.transport)
+	if err != nil {
+		return ctx, func() {}, NewAnalysisError(ErrInvalidCredentials, "invalid credentials", err)
+	}
+	return withCredentialSession(ctx, session), session.close, nil
+}
+
+// sessionBody releases the credential session when the body is closed
+type sessionBody struct {
+	io.ReadCloser
+	closeSession func()
+}
+
+// Close implements io.Closer
+func (b *sessionBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closeSession()
+	return err
+}
+
+// fetchPage fetches the webpage with retry logic. The returned tracer
+// covers the attempt that produced the response. Retries are added as
+// events to the span of the context.
+func (a *DefaultPageAnalyzer) fetchPage(ctx context.Context, s *settings, url *url.URL) (*http.Response, *requestTracer, error) {
 	var resp *http.Response
+	span := trace.SpanFromContext(ctx)
 
 	// Try with retry logic
-	for i := 0; i < a.config.RetryAttempts; i++ {
-		req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
+	for i := 0; i < s.config.RetryAttempts; i++ {
+		traceCtx, tracer := newRequestTracer(ctx)
+		req, err := http.NewRequestWithContext(traceCtx, "GET", url.String(), nil)
 		if err != nil {
-			return nil, NewAnalysisError(ErrFetchFailed, "failed to create request", err)
+			return nil, nil, NewAnalysisError(ErrFetchFailed, "failed to create request", err)
 		}
 
-		req.Header.Set("User-Agent", a.config.UserAgent)
-		resp, err = a.client.Do(req)
+		req.Header.Set("User-Agent", s.config.UserAgent)
+		resp, err = s.client.Do(req)
 		if err != nil {
-			if i == a.config.RetryAttempts-1 {
-				return nil, NewAnalysisError(ErrFetchFailed, "failed to fetch page", err)
+			if ctx.Err() != nil || isTimeout(err) {
+				return nil, nil, contextError(err, "stopped fetching page")
+			}
+			// Blocked addresses stay blocked, so there is no point in retrying
+			if isBlocked(err) {
+				return nil, nil, NewAnalysisError(ErrBlockedAddress, "the page is on a blocked address", err)
+			}
+			if i == s.config.RetryAttempts-1 {
+				return nil, nil, NewAnalysisError(ErrFetchFailed, "failed to fetch page", err)
+			}
+			span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", i+1), attribute.String("error", err.Error())))
+			if err := sleepContext(ctx, time.Duration(1<<uint(i))*time.Second); err != nil {
+				return nil, nil, contextError(err, "stopped retrying page fetch")
 			}
-			time.Sleep(time.Duration(1<<uint(i)) * time.Second)
 			continue
 		}
 
 		if resp.StatusCode >= 500 {
 			resp.Body.Close()
-			if i == a.config.RetryAttempts-1 {
-				return nil, NewAnalysisError(ErrFetchFailed, "server error", nil)
+			if i == s.config.RetryAttempts-1 {
+				return nil, nil, NewAnalysisError(ErrFetchFailed, "server error", &StatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+			}
+			span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", i+1), semconv.HTTPResponseStatusCode(resp.StatusCode)))
+			if err := sleepContext(ctx, time.Duration(1<<uint(i))*time.Second); err != nil {
+				return nil, nil, contextError(err, "stopped retrying page fetch")
 			}
-			time.Sleep(time.Duration(1<<uint(i)) * time.Second)
 			continue
 		}
 
-		return resp, nil
+		if i > 0 {
+			span.SetAttributes(semconv.HTTPRequestResendCount(i))
+		}
+		return resp, tracer, nil
+	}
+
+	return nil, nil, NewAnalysisError(ErrFetchFailed, "max retries exceeded", nil)
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// contextError converts a failure caused by a deadline or cancellation into
+// a TIMEOUT or CANCELED analysis error
+func contextError(err error, message string) *AnalysisError {
+	if isTimeout(err) {
+		return NewAnalysisError(ErrTimeout, message, err)
 	}
+	return NewAnalysisError(ErrCanceled, message, err)
+}
+
+// isBlocked reports whether a request failed because the address guard
+// refused to connect
+func isBlocked(err error) bool {
+	var blocked *BlockedAddressError
+	return errors.As(err, &blocked)
+}
 
-	return nil, NewAnalysisError(ErrFetchFailed, "max retries exceeded", nil)
+// isTimeout reports whether a request failed because a deadline passed,
+// either the client timeout or the deadline of the analysis context
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 // isLoginForm checks if a form is likely a login form
@@ -2,19 +2,43 @@ package analyzer
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"home24/internal/metrics"
+	"home24/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"golang.org/x/net/html"
 )
 
+// testConfig returns the default configuration with loopback addresses
+// allowed, as the test servers listen on them
+func testConfig() AnalyzerConfig {
+	var config = DefaultConfig()
+	config.Network.AllowedNetworks = []string{"127.0.0.0/8"}
+	return config
+}
+
 // Test create a new analyzer
 func TestNew(t *testing.T) {
 	var config = DefaultConfig()
-	var analyzer = NewDefaultPageAnalyzer(&config)
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
 
 	if analyzer == nil {
 		t.Fatal("expected analyzer to be non-nil")
@@ -65,8 +89,8 @@ func TestAnalyzeParallel(t *testing.T) {
 	}))
 	defer errorServer.Close()
 
-	var config = DefaultConfig()
-	var analyzer = NewDefaultPageAnalyzer(&config)
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
 
 	// Test with context timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -165,8 +189,8 @@ func TestParallelLinkProcessing(t *testing.T) {
 	}))
 	defer testServer.Close()
 
-	var config = DefaultConfig()
-	var analyzer = NewDefaultPageAnalyzer(&config)
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
 
 	// Test with a longer timeout to ensure all links are checked
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -224,8 +248,8 @@ func TestConcurrentFormAnalysis(t *testing.T) {
 	}))
 	defer server.Close()
 
-	var config = DefaultConfig()
-	var analyzer = NewDefaultPageAnalyzer(&config)
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
 
 	var result, err = analyzer.Analyze(context.Background(), server.URL)
 	if err != nil {
@@ -240,8 +264,8 @@ func TestConcurrentFormAnalysis(t *testing.T) {
 
 // Test error handling
 func TestAnalyzeError(t *testing.T) {
-	var config = DefaultConfig()
-	var analyzer = NewDefaultPageAnalyzer(&config)
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
 
 	// Test with invalid URL
 	var _, err = analyzer.Analyze(context.Background(), "http://invalid-url-that-does-not-exist.example")
@@ -264,3 +288,827 @@ func ParseHTMLString(htmlString string) (*html.Node, error) {
 	var reader = strings.NewReader(htmlString)
 	return html.Parse(reader)
 }
+
+// Test request phase timings are recorded for the page and its links
+func TestAnalyzeTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><body><a href="` + "http://" + r.Host + `/slow">Slow</a></body></html>`))
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var result, err = analyzer.Analyze(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Error analyzing page: %v", err)
+	}
+
+	if result.Timings.TimeToFirstByte < 20*time.Millisecond {
+		t.Errorf("Expected time to first byte of at least 20ms, got %s", result.Timings.TimeToFirstByte)
+	}
+	if result.Timings.TCPConnect <= 0 {
+		t.Errorf("Expected TCP connect time to be recorded, got %s", result.Timings.TCPConnect)
+	}
+	if result.Duration < result.Timings.Total {
+		t.Errorf("Expected analysis duration %s to cover fetch time %s", result.Duration, result.Timings.Total)
+	}
+
+	if len(result.Links) != 1 {
+		t.Fatalf("Expected 1 link, got %d", len(result.Links))
+	}
+	if result.Links[0].Timings.TimeToFirstByte < 50*time.Millisecond {
+		t.Errorf("Expected link time to first byte of at least 50ms, got %s", result.Links[0].Timings.TimeToFirstByte)
+	}
+}
+
+// Test credentials are sent to in-scope hosts only
+func TestAnalyzeCredentialsScope(t *testing.T) {
+	var leaked = make(chan string, 10)
+	externalServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-Preview") != "" || r.Header.Get("Cookie") != "" {
+			leaked <- r.URL.String()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer externalServer.Close()
+
+	mainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user, pass, ok = r.BasicAuth()
+		if !ok || user != "staging" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Preview") != "1" {
+			t.Errorf("Expected X-Preview header on %s", r.URL.Path)
+		}
+		if cookie, err := r.Cookie("flag"); err != nil || cookie.Value != "on" {
+			t.Errorf("Expected flag cookie on %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Staging</title></head><body>
+			<a href="` + externalServer.URL + `/page">External</a>
+		</body></html>`))
+	}))
+	defer mainServer.Close()
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	ctx := WithCredentials(context.Background(), Credentials{
+		Headers:   map[string]string{"X-Preview": "1"},
+		Cookies:   map[string]string{"flag": "on"},
+		BasicAuth: &BasicAuth{Username: "staging", Password: "secret"},
+	})
+
+	var result, err = analyzer.Analyze(ctx, mainServer.URL)
+	if err != nil {
+		t.Fatalf("Error analyzing page: %v", err)
+	}
+	if result.Title != "Staging" {
+		t.Errorf("Expected 'Staging', got %s", result.Title)
+	}
+	if result.AccessibleLinks != 1 {
+		t.Errorf("Expected 1 accessible link, got %d", result.AccessibleLinks)
+	}
+
+	select {
+	case u := <-leaked:
+		t.Errorf("Credentials leaked to external link %s", u)
+	default:
+	}
+}
+
+// Test that credentials given with an analysis cannot name certificate files
+func TestAnalyzeRejectsRequestCertificateFiles(t *testing.T) {
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	ctx := WithCredentials(context.Background(), Credentials{
+		ClientCert: &ClientCertificate{CertFile: "/etc/hostname", KeyFile: "/etc/hostname"},
+	})
+
+	var _, err = analyzer.Analyze(ctx, "http://127.0.0.1:1")
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrInvalidCredentials {
+		t.Fatalf("Expected INVALID_CREDENTIALS, got %v", err)
+	}
+	if !errors.Is(err, errCertificateFiles) {
+		t.Errorf("Expected the certificate files to be refused, got %v", err)
+	}
+
+	var decoded ClientCertificate
+	if err := json.Unmarshal([]byte(`{"cert_file":"/etc/passwd","cert_pem":"pem"}`), &decoded); err != nil {
+		t.Fatalf("Error decoding certificate: %v", err)
+	}
+	if decoded.CertFile != "" || decoded.CertPEM != "pem" {
+		t.Errorf("Expected only the inline certificate to be decoded, got %+v", decoded)
+	}
+}
+
+// Test DNS overrides and per-host insecure TLS
+func TestAnalyzeNetworkOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>New Build</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	_, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "https://"), ":")
+
+	var config = testConfig()
+	config.RetryAttempts = 1
+	config.Network = NetworkConfig{
+		Resolve: map[string]string{
+			"www.example.de:" + port: "127.0.0.1",
+			"other.example.de":       "127.0.0.1",
+		},
+		InsecureSkipVerifyHosts: []string{"www.example.de"},
+		AllowedNetworks:         []string{"127.0.0.0/8"},
+	}
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var result, err = analyzer.Analyze(context.Background(), "https://www.example.de:"+port+"/")
+	if err != nil {
+		t.Fatalf("Error analyzing page: %v", err)
+	}
+	if result.Title != "New Build" {
+		t.Errorf("Expected 'New Build', got %s", result.Title)
+	}
+
+	// Hosts that aren't listed as insecure are still verified
+	_, err = analyzer.Analyze(context.Background(), "https://other.example.de:"+port+"/")
+	if err == nil {
+		t.Error("Expected certificate verification error for other.example.de, got nil")
+	}
+}
+
+// Test invalid network options are reported instead of ignored
+func TestAnalyzeInvalidNetworkConfig(t *testing.T) {
+	var config = testConfig()
+	config.Network.Resolve = map[string]string{"www.example.de": "not-an-ip"}
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var _, err = analyzer.Analyze(context.Background(), "http://www.example.de")
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrInvalidConfig {
+		t.Errorf("Expected %s error, got %v", ErrInvalidConfig, err)
+	}
+}
+
+// Test internal addresses are refused, also behind a redirect or a link,
+// unless their range is allowed
+func TestAnalyzeBlockedAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		case "/":
+			w.Write([]byte(`<html><head><title>Links</title></head><body>
+				<a href="http://169.254.169.254/latest/meta-data/">Metadata</a>
+			</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var config = DefaultConfig()
+	config.RetryAttempts = 1
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var _, err = analyzer.Analyze(context.Background(), server.URL)
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrBlockedAddress {
+		t.Fatalf("Expected %s error, got %v", ErrBlockedAddress, err)
+	}
+	if !strings.Contains(err.Error(), "connecting to 127.0.0.1 is not allowed") {
+		t.Errorf("Expected the blocked address in %q", err)
+	}
+
+	// Allowing loopback doesn't allow the metadata service it redirects to
+	config.Network.AllowedNetworks = []string{"127.0.0.1"}
+	analyzer = NewDefaultPageAnalyzer(&config, nil)
+	_, err = analyzer.Analyze(context.Background(), server.URL+"/redirect")
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrBlockedAddress {
+		t.Fatalf("Expected %s error for the redirect, got %v", ErrBlockedAddress, err)
+	}
+
+	var result, analyzeErr = analyzer.Analyze(context.Background(), server.URL)
+	if analyzeErr != nil {
+		t.Fatalf("Error analyzing page: %v", analyzeErr)
+	}
+	if len(result.Links) != 1 || result.AccessibleLinks != 0 {
+		t.Errorf("Expected the link to the metadata service to be inaccessible, got %+v", result.Links)
+	}
+
+	config.Network.AllowedNetworks = []string{"10.0.0.0/33"}
+	if err := config.Network.Validate(); err == nil || !strings.Contains(err.Error(), "allowedNetworks") {
+		t.Errorf("Expected an invalid allowedNetworks error, got %v", err)
+	}
+}
+
+// Test host names resolving to blocked addresses are refused behind a
+// proxy, which itself may be internal
+func TestAnalyzeBlockedAddressesBehindProxy(t *testing.T) {
+	var proxied bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+		w.Write([]byte(`<html><head><title>Proxied</title></head></html>`))
+	}))
+	defer proxy.Close()
+
+	var config = DefaultConfig()
+	config.RetryAttempts = 1
+	config.Network.ProxyURL = proxy.URL
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var _, err = analyzer.Analyze(context.Background(), "http://localhost/")
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrBlockedAddress {
+		t.Fatalf("Expected %s error, got %v", ErrBlockedAddress, err)
+	}
+	if proxied {
+		t.Error("Expected the request not to reach the proxy")
+	}
+}
+
+// Test an internal proxy is dialed for the requests sent through it, while
+// other dials to its address and to IPv6 ranges embedding IPv4 addresses are
+// still checked
+func TestAnalyzeThroughInternalProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Proxied</title></head></html>`))
+	}))
+	defer proxy.Close()
+
+	var config = DefaultConfig()
+	config.RetryAttempts = 1
+	config.Network.ProxyURL = proxy.URL
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var result, err = analyzer.Analyze(context.Background(), "http://203.0.113.10/")
+	if err != nil {
+		t.Fatalf("Error analyzing page through the proxy: %v", err)
+	}
+	if result.Title != "Proxied" {
+		t.Errorf("Expected the page served by the proxy, got %q", result.Title)
+	}
+
+	var guard = &addressGuard{}
+	var dialed string
+	var dial = guard.dialContext(
+		func(ctx context.Context, network, addr string) (net.Conn, error) { dialed = "guarded"; return nil, nil },
+		func(ctx context.Context, network, addr string) (net.Conn, error) { dialed = "direct"; return nil, nil },
+	)
+	var proxyAddr = proxy.Listener.Addr().String()
+	var marked = &proxyDial{addr: proxyAddr}
+	for _, test := range []struct {
+		ctx      context.Context
+		addr     string
+		expected string
+	}{
+		{context.WithValue(context.Background(), proxyDialKey{}, marked), proxyAddr, "direct"},
+		{context.WithValue(context.Background(), proxyDialKey{}, &proxyDial{}), proxyAddr, "guarded"},
+		{context.Background(), proxyAddr, "guarded"},
+		{context.WithValue(context.Background(), proxyDialKey{}, marked), "10.0.0.1:80", "guarded"},
+	} {
+		dial(test.ctx, "tcp", test.addr)
+		if dialed != test.expected {
+			t.Errorf("Expected the dial to %s to be %s, got %s", test.addr, test.expected, dialed)
+		}
+	}
+
+	var blocked *BlockedAddressError
+	if err := guard.check("", netip.MustParseAddr("2002:a9fe:a9fe::1")); !errors.As(err, &blocked) {
+		t.Errorf("Expected the 6to4 address of the metadata service to be blocked, got %v", err)
+	}
+}
+
+// Test progress events are reported in order
+func TestAnalyzeEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><body>
+			<a href="http://` + r.Host + `/a">A</a>
+			<a href="http://` + r.Host + `/b">B</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var mu sync.Mutex
+	var events []Event
+	ctx := WithEventHandler(context.Background(), func(e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+
+	if _, err := analyzer.Analyze(ctx, server.URL); err != nil {
+		t.Fatalf("Error analyzing page: %v", err)
+	}
+
+	var types []EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	var expected = []EventType{EventFetched, EventParsed, EventLinkChecked, EventLinkChecked, EventDone}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Fatalf("Expected events %v, got %v", expected, types)
+		}
+	}
+	if events[3].LinksChecked != 2 || events[3].LinksTotal != 2 {
+		t.Errorf("Expected 2 of 2 links checked, got %d of %d", events[3].LinksChecked, events[3].LinksTotal)
+	}
+	if events[4].Result == nil || events[4].Result.AccessibleLinks != 2 {
+		t.Error("Expected the done event to carry the final result")
+	}
+}
+
+// Test canceling the context stops the analysis with a CANCELED error
+func TestAnalyzeCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var _, err = analyzer.Analyze(ctx, server.URL)
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrCanceled {
+		t.Errorf("Expected %s error, got %v", ErrCanceled, err)
+	}
+}
+
+// Test a reload applies to later analyses and an invalid configuration is
+// rejected while the current one stays active
+func TestReload(t *testing.T) {
+	var userAgents = make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.UserAgent()
+		w.Write([]byte(`<html><head><title>Reload</title></head></html>`))
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var next = testConfig()
+	next.UserAgent = "Reloaded/1.0"
+	if err := analyzer.Reload(next); err != nil {
+		t.Fatalf("Error reloading: %v", err)
+	}
+
+	var invalid = next
+	invalid.UserAgent = "Invalid/1.0"
+	invalid.Network.ProxyURL = "::not a proxy"
+	var err = analyzer.Reload(invalid)
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrInvalidConfig {
+		t.Fatalf("Expected INVALID_CONFIG, got %v", err)
+	}
+
+	if _, err := analyzer.Analyze(context.Background(), server.URL); err != nil {
+		t.Fatalf("Error analyzing: %v", err)
+	}
+	if ua := <-userAgents; ua != "Reloaded/1.0" {
+		t.Errorf("Expected the reloaded user agent, got %q", ua)
+	}
+	if analyzer.Config().UserAgent != "Reloaded/1.0" || analyzer.ConfigError() != nil {
+		t.Errorf("Expected the valid configuration to stay active, got %+v", analyzer.Config())
+	}
+}
+
+// metricValues returns the values of a gathered counter or the sample counts
+// of a histogram by their joined label values
+func metricValues(t *testing.T, registry *prometheus.Registry, name string) map[string]float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Error gathering metrics: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			var labels []string
+			for _, label := range m.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			value := m.GetCounter().GetValue()
+			if m.GetGauge() != nil {
+				value = m.GetGauge().GetValue()
+			}
+			if m.GetHistogram() != nil {
+				value = float64(m.GetHistogram().GetSampleCount())
+			}
+			values[strings.Join(labels, ",")] = value
+		}
+	}
+	return values
+}
+
+// Test analyzers record to their own registry with their prefix, with error
+// codes, link status classes and stages as labels
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><h2>A</h2><h2>B</h2><a href="/ok">OK</a><a href="/missing">Missing</a></body></html>`))
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	var registry = prometheus.NewRegistry()
+	var analyzer = NewDefaultPageAnalyzer(&config, metrics.New(registry, "first"))
+	var otherRegistry = prometheus.NewRegistry()
+	NewDefaultPageAnalyzer(&config, metrics.New(otherRegistry, "second"))
+
+	for i := 0; i < 2; i++ {
+		if _, err := analyzer.Analyze(context.Background(), server.URL); err != nil {
+			t.Fatalf("Error analyzing page: %v", err)
+		}
+	}
+	if _, err := analyzer.Analyze(context.Background(), "::invalid"); err == nil {
+		t.Fatal("Expected an error for the invalid URL")
+	}
+
+	expected := map[string]map[string]float64{
+		"first_requests_total":         {"": 3},
+		"first_errors_total":           {ErrInvalidURL: 1},
+		"first_link_checks_total":      {"2xx": 2, "4xx": 2},
+		"first_links_total":            {"internal": 4},
+		"first_headings_total":         {"h2": 4},
+		"first_stage_duration_seconds": {StageFetch: 2, StageParse: 2, StageCheckLinks: 2},
+	}
+	for name, want := range expected {
+		got := metricValues(t, registry, name)
+		for labels, value := range want {
+			if got[labels] != value {
+				t.Errorf("Expected %s{%s} to be %v, got %v", name, labels, value, got[labels])
+			}
+		}
+	}
+
+	if got := metricValues(t, otherRegistry, "second_requests_total"); got[""] != 0 {
+		t.Errorf("Expected the second analyzer to record nothing, got %v", got)
+	}
+}
+
+// Test an analysis creates spans for the fetch with its retries, the parsing
+// and each link check, within the trace of the caller
+func TestTracing(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			if attempts.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`<html><body><a href="/ok">OK</a><a href="/missing">Missing</a></body></html>`))
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	// The caller's span, e.g. propagated from an incoming request
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if _, err := analyzer.Analyze(ctx, server.URL); err != nil {
+		t.Fatalf("Error analyzing page: %v", err)
+	}
+	parent.End()
+
+	spans := make(map[string][]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("Span %s is not part of the caller's trace", span.Name)
+		}
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+	if len(spans["analyze"]) != 1 || len(spans["fetch"]) != 1 || len(spans["parse"]) != 1 || len(spans["check_link"]) != 2 {
+		t.Fatalf("Unexpected spans %v", spans)
+	}
+
+	analyze := spans["analyze"][0]
+	if analyze.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the analyze span to be a child of the caller's span")
+	}
+	for _, name := range []string{"fetch", "parse", "check_link"} {
+		for _, span := range spans[name] {
+			if span.Parent.SpanID() != analyze.SpanContext.SpanID() {
+				t.Errorf("Expected %s to be a child of the analyze span", name)
+			}
+		}
+	}
+
+	fetch := spans["fetch"][0]
+	if len(fetch.Events) != 1 || fetch.Events[0].Name != "retry" {
+		t.Errorf("Expected one retry event on the fetch span, got %v", fetch.Events)
+	}
+	if attr := spanAttribute(fetch, "http.request.resend_count"); attr.AsInt64() != 1 {
+		t.Errorf("Expected a resend count of 1, got %v", attr.Emit())
+	}
+
+	for _, span := range spans["check_link"] {
+		url := spanAttribute(span, "url.full").AsString()
+		class := spanAttribute(span, "analyzer.link.status_class").AsString()
+		switch {
+		case strings.HasSuffix(url, "/ok"):
+			if class != "2xx" || span.Status.Code != codes.Unset {
+				t.Errorf("Unexpected span for %s: %s, %v", url, class, span.Status)
+			}
+		case strings.HasSuffix(url, "/missing"):
+			if class != "4xx" || span.Status.Code != codes.Error || spanAttribute(span, "http.response.status_code").AsInt64() != 404 {
+				t.Errorf("Unexpected span for %s: %s, %v", url, class, span.Status)
+			}
+		default:
+			t.Errorf("Unexpected link span for %s", url)
+		}
+	}
+}
+
+// spanAttribute returns the value of an attribute of a span
+func spanAttribute(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// Test analyses over the limit wait in the queue and fail with OVERLOADED
+// when it is full or they waited too long
+func TestAdmission(t *testing.T) {
+	var started = make(chan struct{}, 1)
+	var unblock = make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-unblock
+		}
+		w.Write([]byte(`<html><head><title>Page</title></head></html>`))
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	config.Admission = AdmissionConfig{MaxConcurrentAnalyses: 1, MaxQueuedAnalyses: 1, QueueTimeout: 100 * time.Millisecond}
+	var registry = prometheus.NewRegistry()
+	var analyzer = NewDefaultPageAnalyzer(&config, metrics.New(registry, "test"))
+
+	var done = make(chan error)
+	go func() {
+		_, err := analyzer.Analyze(context.Background(), server.URL+"/slow")
+		done <- err
+	}()
+	<-started
+
+	// The second analysis waits, the third finds the queue full
+	var queued = make(chan error)
+	go func() {
+		_, err := analyzer.Analyze(context.Background(), server.URL)
+		queued <- err
+	}()
+	for metricValues(t, registry, "test_analysis_queue_depth")[""] != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	var _, err = analyzer.Analyze(context.Background(), server.URL)
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrOverloaded || !errors.Is(err, errQueueFull) {
+		t.Errorf("Expected %s for the full queue, got %v", ErrOverloaded, err)
+	}
+	err = <-queued
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrOverloaded || !errors.Is(err, errQueueTimeout) {
+		t.Errorf("Expected %s after the queue timeout, got %v", ErrOverloaded, err)
+	}
+	if got := metricValues(t, registry, "test_analyses_in_flight")[""]; got != 1 {
+		t.Errorf("Expected 1 analysis in flight, got %v", got)
+	}
+
+	// Raising the limit admits waiting analyses right away
+	go func() {
+		_, err := analyzer.Analyze(context.Background(), server.URL)
+		queued <- err
+	}()
+	for metricValues(t, registry, "test_analysis_queue_depth")[""] != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	config.Admission.MaxConcurrentAnalyses = 2
+	if err := analyzer.Reload(config); err != nil {
+		t.Fatalf("Error reloading: %v", err)
+	}
+	if err := <-queued; err != nil {
+		t.Errorf("Expected the waiting analysis to run after the reload, got %v", err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Errorf("Error analyzing slow page: %v", err)
+	}
+	if got := metricValues(t, registry, "test_analyses_in_flight")[""]; got != 0 {
+		t.Errorf("Expected no analyses in flight, got %v", got)
+	}
+}
+
+// Test the outbound requests of concurrent analyses share one limit
+func TestOutboundLimit(t *testing.T) {
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<html><body><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	config.Admission.MaxOutboundRequests = 2
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := analyzer.Analyze(context.Background(), server.URL)
+			if err != nil {
+				t.Errorf("Error analyzing page: %v", err)
+				return
+			}
+			if result.AccessibleLinks != 3 {
+				t.Errorf("Expected 3 accessible links, got %d", result.AccessibleLinks)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", got)
+	}
+}
+
+// Test an analysis stopped while checking links carries the links checked
+// so far as a partial result
+func TestAnalyzePartialResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Partial</title></head><body><a href="/fast">Fast</a><a href="/slow">Slow</a></body></html>`))
+		case "/slow":
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = WithEventHandler(ctx, func(event Event) {
+		if event.Type == EventLinkChecked {
+			cancel()
+		}
+	})
+
+	var _, err = analyzer.Analyze(ctx, server.URL)
+	var analysisErr *AnalysisError
+	if !errors.As(err, &analysisErr) || analysisErr.Code != ErrCanceled {
+		t.Fatalf("Expected %s error, got %v", ErrCanceled, err)
+	}
+	partial := analysisErr.Partial
+	if partial == nil || !partial.Partial {
+		t.Fatalf("Expected a partial result, got %+v", partial)
+	}
+	if partial.Title != "Partial" {
+		t.Errorf("Expected the page title, got %q", partial.Title)
+	}
+	if len(partial.Links) != 1 || partial.Links[0].URL != "/fast" || partial.AccessibleLinks != 1 {
+		t.Errorf("Expected only the checked link, got %+v", partial.Links)
+	}
+}
+
+// Test the debug state lists running and waiting analyses with their
+// progress and the use of the limits
+func TestDebugState(t *testing.T) {
+	var started = make(chan struct{}, 1)
+	var unblock = make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			started <- struct{}{}
+			<-unblock
+		case "/":
+			w.Write([]byte(`<html><body><a href="/fast">Fast</a><a href="/slow">Slow</a></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var config = testConfig()
+	config.Admission = AdmissionConfig{MaxConcurrentAnalyses: 1, MaxQueuedAnalyses: 1, QueueTimeout: time.Minute, MaxOutboundRequests: 5}
+	var analyzer = NewDefaultPageAnalyzer(&config, nil)
+
+	var done = make(chan error, 2)
+	go func() {
+		ctx := logger.WithAttrs(context.Background(), slog.String("job_id", "job-1"))
+		_, err := analyzer.Analyze(ctx, server.URL+"/")
+		done <- err
+	}()
+	<-started
+	go func() {
+		_, err := analyzer.Analyze(context.Background(), server.URL+"/waiting")
+		done <- err
+	}()
+
+	var state DebugState
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		state = analyzer.DebugState()
+		if len(state.Analyses) == 2 && state.Analyses[0].LinksChecked == 1 && state.AnalysisSlots.Waiting == 1 &&
+			state.Analyses[0].LinkSlots != nil && state.Analyses[0].LinkSlots.InUse == 1 {
+			break
+		}
+	}
+	if len(state.Analyses) != 2 {
+		t.Fatalf("Expected 2 analyses in flight, got %+v", state.Analyses)
+	}
+	running, waiting := state.Analyses[0], state.Analyses[1]
+	if running.URL != server.URL+"/" || running.Stage != StageCheckingLinks || running.LinksChecked != 1 || running.LinksTotal != 2 ||
+		running.StartedAt == nil || running.Attrs["job_id"] != "job-1" || running.Age <= 0 {
+		t.Errorf("Unexpected running analysis %+v", running)
+	}
+	if running.LinkSlots == nil || *running.LinkSlots != (SlotState{Limit: config.MaxConcurrentLinks, InUse: 1}) {
+		t.Errorf("Expected the slow link to hold a link slot, got %+v", running.LinkSlots)
+	}
+	if waiting.URL != server.URL+"/waiting" || waiting.Stage != StageQueued || waiting.StartedAt != nil || waiting.LinkSlots != nil {
+		t.Errorf("Unexpected waiting analysis %+v", waiting)
+	}
+	if state.AnalysisSlots != (SlotState{Limit: 1, InUse: 1, Waiting: 1}) {
+		t.Errorf("Unexpected analysis slots %+v", state.AnalysisSlots)
+	}
+	if state.OutboundSlots != (SlotState{Limit: 5, InUse: 1}) {
+		t.Errorf("Unexpected outbound slots %+v", state.OutboundSlots)
+	}
+	if state.MaxConcurrentLinks != config.MaxConcurrentLinks || state.Connections.Open < 1 {
+		t.Errorf("Expected the link limit and open connections, got %+v", state)
+	}
+
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("Error analyzing page: %v", err)
+		}
+	}
+	if state := analyzer.DebugState(); len(state.Analyses) != 0 || state.AnalysisSlots.InUse != 0 {
+		t.Errorf("Expected no analyses in flight, got %+v", state)
+	}
+}
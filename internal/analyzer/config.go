@@ -5,18 +5,27 @@ import "time"
 // AnalyzerConfig holds all configuration options for the PageAnalyzer
 type AnalyzerConfig struct {
 	// HTTP client configuration
-	Timeout            time.Duration
-	MaxConcurrentLinks int
-	UserAgent          string
-	RetryAttempts      int
+	Timeout            time.Duration `yaml:"timeout"`
+	MaxConcurrentLinks int           `yaml:"maxConcurrentLinks"`
+	UserAgent          string        `yaml:"userAgent"`
+	RetryAttempts      int           `yaml:"retryAttempts"`
 
 	// Analysis configuration
-	MaxLinksPerPage int
-	MaxDepth        int
+	MaxLinksPerPage int `yaml:"maxLinksPerPage"`
+	MaxDepth        int `yaml:"maxDepth"`
 
 	// Metrics configuration
-	EnableMetrics bool
-	MetricsPrefix string
+	EnableMetrics bool   `yaml:"enableMetrics"`
+	MetricsPrefix string `yaml:"metricsPrefix"`
+
+	// Outbound network options: proxy, DNS overrides and TLS trust
+	Network NetworkConfig `yaml:"network"`
+
+	// Credentials applied to requests for matching hosts on every analysis
+	Credentials []Credentials `yaml:"credentials"`
+
+	// Process-wide limits on concurrent analyses and outbound requests
+	Admission AdmissionConfig `yaml:"admission"`
 }
 
 // DefaultConfig returns a configuration with sensible defaults
@@ -30,5 +39,11 @@ func DefaultConfig() AnalyzerConfig {
 		MaxDepth:           2,
 		EnableMetrics:      true,
 		MetricsPrefix:      "webpage_analyzer",
+		Admission: AdmissionConfig{
+			MaxConcurrentAnalyses: 20,
+			MaxQueuedAnalyses:     100,
+			QueueTimeout:          30 * time.Second,
+			MaxOutboundRequests:   200,
+		},
 	}
 }
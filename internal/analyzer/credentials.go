@@ -0,0 +1,229 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+)
+
+// Credentials holds request headers, cookies and authentication that are
+// attached to outgoing requests for a set of hosts. Hosts are matched
+// exactly or, when prefixed with "*.", against any subdomain. Credentials
+// without hosts apply to the host of the analyzed URL only, so they are
+// never sent to external links.
+type Credentials struct {
+	Hosts       []string           `yaml:"hosts" json:"hosts,omitempty"`
+	Headers     map[string]string  `yaml:"headers" json:"headers,omitempty"`
+	Cookies     map[string]string  `yaml:"cookies" json:"cookies,omitempty"`
+	BasicAuth   *BasicAuth         `yaml:"basicAuth" json:"basic_auth,omitempty"`
+	BearerToken string             `yaml:"bearerToken" json:"bearer_token,omitempty"`
+	ClientCert  *ClientCertificate `yaml:"clientCert" json:"client_cert,omitempty"`
+}
+
+// BasicAuth holds HTTP basic authentication credentials
+type BasicAuth struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+}
+
+// ClientCertificate holds a TLS client certificate for mTLS, either as file
+// paths or as inline PEM data. File paths can only be configured; they are
+// not part of the JSON form used by requests.
+type ClientCertificate struct {
+	CertFile string `yaml:"certFile" json:"-"`
+	KeyFile  string `yaml:"keyFile" json:"-"`
+	CertPEM  string `yaml:"certPEM" json:"cert_pem,omitempty"`
+	KeyPEM   string `yaml:"keyPEM" json:"key_pem,omitempty"`
+}
+
+// errCertificateFiles is returned for credentials given with an analysis
+// that name certificate files, which would let callers read local files
+var errCertificateFiles = errors.New("client certificates given with a request must be inline PEM")
+
+// Load parses the certificate and key
+func (c *ClientCertificate) Load() (tls.Certificate, error) {
+	if c.CertPEM != "" || c.KeyPEM != "" {
+		return tls.X509KeyPair([]byte(c.CertPEM), []byte(c.KeyPEM))
+	}
+	return tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+}
+
+// MatchesHost reports whether the credentials apply to a request host
+// ("host" or "host:port"). Patterns that include a port only match that
+// port; patterns without one match the host on any port.
+func (c *Credentials) MatchesHost(hostport string) bool {
+	hostport = strings.ToLower(hostport)
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+
+	for _, pattern := range c.Hosts {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		candidate := host
+		if _, _, err := net.SplitHostPort(pattern); err == nil {
+			candidate = hostport
+		}
+		if pattern == candidate {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(candidate, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+type credentialsKey struct{}
+
+// WithCredentials returns a context carrying credentials for a single
+// analysis. They are applied in addition to the configured credentials.
+func WithCredentials(ctx context.Context, creds ...Credentials) context.Context {
+	existing, _ := ctx.Value(credentialsKey{}).([]Credentials)
+	merged := append(append([]Credentials{}, existing...), creds...)
+	return context.WithValue(ctx, credentialsKey{}, merged)
+}
+
+// credentialsFromContext returns the credentials attached to the context.
+// Only inline certificates are accepted from there.
+func credentialsFromContext(ctx context.Context) ([]Credentials, error) {
+	creds, _ := ctx.Value(credentialsKey{}).([]Credentials)
+	for _, c := range creds {
+		if c.ClientCert != nil && (c.ClientCert.CertFile != "" || c.ClientCert.KeyFile != "") {
+			return nil, errCertificateFiles
+		}
+	}
+	return creds, nil
+}
+
+// credentialSession holds the credentials, cookie jar and mTLS transports
+// used for the requests of one analysis
+type credentialSession struct {
+	creds      []Credentials
+	transports map[int]*http.Transport
+	jar        *cookiejar.Jar
+}
+
+type sessionKey struct{}
+
+// newCredentialSession prepares the credentials for an analysis of the
+// given host. Credentials without hosts are scoped to that host, including
+// its port when the analyzed URL names one.
+func newCredentialSession(creds []Credentials, targetHost string, base *http.Transport) (*credentialSession, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &credentialSession{
+		transports: make(map[int]*http.Transport),
+		jar:        jar,
+	}
+	for i, c := range creds {
+		if len(c.Hosts) == 0 {
+			c.Hosts = []string{targetHost}
+		}
+		if c.ClientCert != nil {
+			cert, err := c.ClientCert.Load()
+			if err != nil {
+				s.close()
+				return nil, fmt.Errorf("loading client certificate: %w", err)
+			}
+			transport := base.Clone()
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+			s.transports[i] = transport
+		}
+		s.creds = append(s.creds, c)
+	}
+	return s, nil
+}
+
+// close releases idle connections held by the mTLS transports
+func (s *credentialSession) close() {
+	for _, t := range s.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+func withCredentialSession(ctx context.Context, s *credentialSession) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+func credentialSessionFromContext(ctx context.Context) *credentialSession {
+	s, _ := ctx.Value(sessionKey{}).(*credentialSession)
+	return s
+}
+
+// credentialTransport applies the credentials of the request's session to
+// requests for in-scope hosts. Requests to any other host go out untouched.
+type credentialTransport struct {
+	base *http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := credentialSessionFromContext(req.Context())
+	if s == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	var transport http.RoundTripper = t.base
+	var matched []Credentials
+	host := req.URL.Host
+	for i, c := range s.creds {
+		if !c.MatchesHost(host) {
+			continue
+		}
+		matched = append(matched, c)
+		if ct, ok := s.transports[i]; ok {
+			transport = ct
+		}
+	}
+	if len(matched) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	configured := make(map[string]bool)
+	for _, c := range matched {
+		for name, value := range c.Headers {
+			req.Header.Set(name, value)
+		}
+		if c.BasicAuth != nil {
+			req.SetBasicAuth(c.BasicAuth.Username, c.BasicAuth.Password)
+		}
+		if c.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+		}
+		for name, value := range c.Cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+			configured[name] = true
+		}
+	}
+
+	// Cookies set by in-scope hosts during this analysis are sent back,
+	// unless a configured cookie of the same name takes precedence
+	for _, cookie := range s.jar.Cookies(req.URL) {
+		if !configured[cookie.Name] {
+			req.AddCookie(cookie)
+		}
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		s.jar.SetCookies(req.URL, cookies)
+	}
+	return resp, nil
+}
@@ -0,0 +1,232 @@
+package analyzer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"home24/pkg/logger"
+)
+
+// The stages of an analysis in flight
+const (
+	StageQueued        = "queued"
+	StageFetching      = "fetching"
+	StageParsing       = "parsing"
+	StageCheckingLinks = "checking_links"
+)
+
+// InFlightAnalysis is an analysis that is waiting for a slot or running.
+// LinkSlots is the use of its MaxConcurrentLinks limit once it checks links.
+type InFlightAnalysis struct {
+	URL          string            `json:"url"`
+	Stage        string            `json:"stage"`
+	QueuedAt     time.Time         `json:"queued_at"`
+	StartedAt    *time.Time        `json:"started_at,omitempty"`
+	Age          time.Duration     `json:"age_ns"`
+	LinksChecked int               `json:"links_checked"`
+	LinksTotal   int               `json:"links_total"`
+	LinkSlots    *SlotState        `json:"link_slots,omitempty"`
+	Attrs        map[string]string `json:"attrs,omitempty"`
+}
+
+// SlotState is the use of a limit; a limit of 0 is off
+type SlotState struct {
+	Limit   int `json:"limit"`
+	InUse   int `json:"in_use"`
+	Waiting int `json:"waiting"`
+}
+
+// ConnectionState describes the outbound connections, including the idle
+// ones kept for reuse, and the limits of that pool
+type ConnectionState struct {
+	Open           int64         `json:"open"`
+	MaxIdle        int           `json:"max_idle"`
+	MaxIdlePerHost int           `json:"max_idle_per_host"`
+	IdleTimeout    time.Duration `json:"idle_timeout_ns"`
+}
+
+// DebugState is a snapshot of what the analyzer is doing, for
+// troubleshooting a slow instance. MaxConcurrentLinks is the configured limit
+// of each analysis; their use of it is reported with the analyses. The
+// analyzer keeps no cache, so there is none to report.
+type DebugState struct {
+	Analyses           []InFlightAnalysis `json:"analyses"`
+	AnalysisSlots      SlotState          `json:"analysis_slots"`
+	OutboundSlots      SlotState          `json:"outbound_slots"`
+	MaxConcurrentLinks int                `json:"max_concurrent_links"`
+	Connections        ConnectionState    `json:"connections"`
+}
+
+// DebugState returns the analyses in flight, oldest first, and the state of
+// the limits and the connection pool
+func (a *DefaultPageAnalyzer) DebugState() DebugState {
+	now := time.Now()
+	state := DebugState{Analyses: []InFlightAnalysis{}}
+	a.inFlight.Range(func(key, _ interface{}) bool {
+		state.Analyses = append(state.Analyses, key.(*inFlight).snapshot(now))
+		return true
+	})
+	sort.Slice(state.Analyses, func(i, j int) bool {
+		return state.Analyses[i].QueuedAt.Before(state.Analyses[j].QueuedAt)
+	})
+
+	s := a.settings.Load()
+	state.AnalysisSlots = a.analyses.state()
+	state.OutboundSlots = a.outbound.state()
+	state.MaxConcurrentLinks = s.config.MaxConcurrentLinks
+	state.Connections = ConnectionState{
+		Open:           a.conns.open.Load(),
+		MaxIdle:        s.transport.MaxIdleConns,
+		MaxIdlePerHost: s.transport.MaxIdleConnsPerHost,
+		IdleTimeout:    s.transport.IdleConnTimeout,
+	}
+	if state.Connections.MaxIdlePerHost == 0 {
+		state.Connections.MaxIdlePerHost = http.DefaultMaxIdleConnsPerHost
+	}
+	return state
+}
+
+// inFlight follows the progress of an analysis through its events
+type inFlight struct {
+	url      string
+	attrs    map[string]string
+	queuedAt time.Time
+
+	mu        sync.Mutex
+	stage     string
+	startedAt time.Time
+	checked   int
+	total     int
+	linkSlots *SlotState
+}
+
+// track registers an analysis until the returned function is called. The
+// returned context reports the analysis' events to it as well as to the
+// handler already in the context.
+func (a *DefaultPageAnalyzer) track(ctx context.Context, targetURL string) (context.Context, *inFlight, func()) {
+	attrs := make(map[string]string)
+	for _, attr := range logger.Attrs(ctx) {
+		if attr.Key != "target_url" {
+			attrs[attr.Key] = attr.Value.String()
+		}
+	}
+	t := &inFlight{url: targetURL, attrs: attrs, queuedAt: time.Now(), stage: StageQueued}
+	a.inFlight.Store(t, struct{}{})
+
+	next, _ := ctx.Value(eventHandlerKey{}).(EventHandler)
+	ctx = WithEventHandler(ctx, func(event Event) {
+		t.follow(event)
+		if next != nil {
+			next(event)
+		}
+	})
+	return ctx, t, func() { a.inFlight.Delete(t) }
+}
+
+// admitted records that the analysis got a slot
+func (t *inFlight) admitted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stage = StageFetching
+	t.startedAt = time.Now()
+}
+
+// checkingLinks records that the analysis starts checking links with at
+// most limit at a time
+func (t *inFlight) checkingLinks(limit, links int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.linkSlots = &SlotState{Limit: limit, Waiting: links}
+}
+
+// linkStarted records that a link got a slot
+func (t *inFlight) linkStarted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.linkSlots.Waiting--
+	t.linkSlots.InUse++
+}
+
+// linkDone records that a link gave its slot back
+func (t *inFlight) linkDone() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.linkSlots.InUse--
+}
+
+// follow updates the progress from an event
+func (t *inFlight) follow(event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch event.Type {
+	case EventFetched:
+		t.stage = StageParsing
+	case EventParsed:
+		t.stage = StageCheckingLinks
+		t.total = event.LinksTotal
+	case EventLinkChecked:
+		t.checked = event.LinksChecked
+	}
+}
+
+// snapshot returns the state of the analysis at now
+func (t *inFlight) snapshot(now time.Time) InFlightAnalysis {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	analysis := InFlightAnalysis{
+		URL:          t.url,
+		Stage:        t.stage,
+		QueuedAt:     t.queuedAt,
+		Age:          now.Sub(t.queuedAt),
+		LinksChecked: t.checked,
+		LinksTotal:   t.total,
+		Attrs:        t.attrs,
+	}
+	if !t.startedAt.IsZero() {
+		startedAt := t.startedAt
+		analysis.StartedAt = &startedAt
+	}
+	if t.linkSlots != nil {
+		linkSlots := *t.linkSlots
+		analysis.LinkSlots = &linkSlots
+	}
+	return analysis
+}
+
+// connCounter counts the open outbound connections of every transport the
+// analyzer has built, so it outlives reloads
+type connCounter struct {
+	open atomic.Int64
+}
+
+// dialContext wraps a dial function to count the connections it opens
+// until they are closed
+func (c *connCounter) dialContext(next func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c.open.Add(1)
+		return &countedConn{Conn: conn, open: &c.open}, nil
+	}
+}
+
+// countedConn decrements the count of open connections once it is closed
+type countedConn struct {
+	net.Conn
+	once sync.Once
+	open *atomic.Int64
+}
+
+// Close implements net.Conn
+func (c *countedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.open.Add(-1) })
+	return err
+}
@@ -4,9 +4,13 @@ import "fmt"
 
 // AnalysisError represents an error that occurred during page analysis
 type AnalysisError struct {
-	Code    string
-	Message string
-	Err     error
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+
+	// Partial is what an analysis found before it was stopped while
+	// checking links, if anything
+	Partial *AnalysisResult `json:"-"`
 }
 
 func (e *AnalysisError) Error() string {
@@ -20,14 +24,30 @@ func (e *AnalysisError) Unwrap() error {
 	return e.Err
 }
 
+// StatusError is the cause of a fetch that failed because the server kept
+// responding with an error status
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
 // Common error codes
 const (
-	ErrInvalidURL      = "INVALID_URL"
-	ErrFetchFailed     = "FETCH_FAILED"
-	ErrParseFailed     = "PARSE_FAILED"
-	ErrTimeout         = "TIMEOUT"
-	ErrMaxLinksReached = "MAX_LINKS_REACHED"
-	ErrMaxDepthReached = "MAX_DEPTH_REACHED"
+	ErrInvalidURL         = "INVALID_URL"
+	ErrFetchFailed        = "FETCH_FAILED"
+	ErrParseFailed        = "PARSE_FAILED"
+	ErrTimeout            = "TIMEOUT"
+	ErrCanceled           = "CANCELED"
+	ErrMaxLinksReached    = "MAX_LINKS_REACHED"
+	ErrMaxDepthReached    = "MAX_DEPTH_REACHED"
+	ErrInvalidCredentials = "INVALID_CREDENTIALS"
+	ErrInvalidConfig      = "INVALID_CONFIG"
+	ErrBlockedAddress     = "BLOCKED_ADDRESS"
+	ErrOverloaded         = "OVERLOADED"
 )
 
 // NewAnalysisError creates a new AnalysisError
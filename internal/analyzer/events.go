@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies a step of an analysis
+type EventType string
+
+// Analysis progress events, in the order they are emitted
+const (
+	EventFetched     EventType = "fetched"
+	EventParsed      EventType = "parsed"
+	EventLinkChecked EventType = "link_checked"
+	EventDone        EventType = "done"
+)
+
+// Event reports the progress of a running analysis. Parsed events carry the
+// result without link accessibility; the done event carries the final one.
+type Event struct {
+	Type         EventType       `json:"type"`
+	URL          string          `json:"url"`
+	Time         time.Time       `json:"time"`
+	StatusCode   int             `json:"status_code,omitempty"`
+	Link         string          `json:"link,omitempty"`
+	Accessible   bool            `json:"accessible,omitempty"`
+	LinksChecked int             `json:"links_checked"`
+	LinksTotal   int             `json:"links_total"`
+	Result       *AnalysisResult `json:"result,omitempty"`
+}
+
+// EventHandler receives analysis events. It is called from the analysis
+// goroutines and must not block for long.
+type EventHandler func(Event)
+
+type eventHandlerKey struct{}
+
+// WithEventHandler returns a context whose analyses report their progress
+// to the handler
+func WithEventHandler(ctx context.Context, handler EventHandler) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, handler)
+}
+
+// emitEvent sends an event to the handler attached to the context, if any
+func emitEvent(ctx context.Context, event Event) {
+	handler, ok := ctx.Value(eventHandlerKey{}).(EventHandler)
+	if !ok || handler == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	handler(event)
+}
@@ -0,0 +1,268 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The ranges the analyzer doesn't connect to unless they are allowed:
+// private, loopback, link-local (including the cloud metadata services at
+// 169.254.169.254 and fd00:ec2::254), shared, multicast and reserved
+// addresses, and the IPv6 ranges that embed IPv4 addresses (NAT64 and 6to4)
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// BlockedAddressError is returned when a connection to an address in a
+// blocked range is refused
+type BlockedAddressError struct {
+	Host string
+	IP   netip.Addr
+}
+
+func (e *BlockedAddressError) Error() string {
+	if e.Host != "" && e.Host != e.IP.String() {
+		return fmt.Sprintf("connecting to %s (%s) is not allowed", e.Host, e.IP)
+	}
+	return fmt.Sprintf("connecting to %s is not allowed", e.IP)
+}
+
+// addressGuard refuses connections to blocked addresses. It checks the
+// address that is actually dialed, after DNS resolution, so neither
+// redirects nor DNS answers that change between lookups get around it.
+type addressGuard struct {
+	allowed []netip.Prefix
+}
+
+// proxyDial is added to the context of every request by proxyDialTransport.
+// The guard's proxy function records the address of the proxy it chose for
+// the request, and only dials of that request to that address skip the
+// check, as the proxy may be internal.
+type proxyDial struct {
+	mu   sync.Mutex
+	addr string
+}
+
+type proxyDialKey struct{}
+
+// proxyDialTransport marks the requests it sends so the guard can tell their
+// proxy dials apart. Without it proxies are checked like any other address.
+type proxyDialTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *proxyDialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), proxyDialKey{}, &proxyDial{})
+	return t.base.RoundTrip(req.WithContext(ctx))
+}
+
+// isProxy reports whether the request the context belongs to is sent
+// through a proxy at addr
+func isProxy(ctx context.Context, addr string) bool {
+	dial, ok := ctx.Value(proxyDialKey{}).(*proxyDial)
+	if !ok {
+		return false
+	}
+	dial.mu.Lock()
+	defer dial.mu.Unlock()
+	return dial.addr == addr
+}
+
+// Guard applies the check of the dialed addresses to HTTP clients other
+// than the analyzer's that send requests to URLs given by callers, such as
+// webhook deliveries
+type Guard struct {
+	guard *addressGuard
+}
+
+// NewGuard creates a guard that lets connections to the allowed CIDR ranges
+// and addresses through
+func NewGuard(allowedNetworks []string) (*Guard, error) {
+	allowed, err := parseNetworks(allowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{guard: &addressGuard{allowed: allowed}}, nil
+}
+
+// Client returns an HTTP client with the timeout that refuses to connect to
+// blocked addresses. The target of every redirect is checked before it is
+// followed.
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
+	g.guard.guardTransport(transport, dialer, nil)
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &proxyDialTransport{base: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return g.CheckURL(req.Context(), req.URL)
+		},
+	}
+}
+
+// CheckURL resolves the host of the URL and returns a BlockedAddressError if
+// any of its addresses is blocked, so such URLs can be rejected up front
+func (g *Guard) CheckURL(ctx context.Context, u *url.URL) error {
+	return g.guard.checkHost(ctx, u.Hostname())
+}
+
+// parseNetworks parses CIDR ranges and single addresses
+func parseNetworks(networks []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, network := range networks {
+		if ip, err := netip.ParseAddr(network); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a CIDR range or IP address", network)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// check returns a BlockedAddressError if the address is blocked and not
+// allowed
+func (g *addressGuard) check(host string, ip netip.Addr) error {
+	ip = ip.Unmap()
+	for _, prefix := range g.allowed {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	for _, prefix := range blockedNetworks {
+		if prefix.Contains(ip) {
+			return &BlockedAddressError{Host: host, IP: ip}
+		}
+	}
+	return nil
+}
+
+// guardTransport makes the transport check the addresses it dials. The
+// dialer is copied, so proxies are still dialed without the check when the
+// transport is wrapped in a proxyDialTransport.
+func (g *addressGuard) guardTransport(transport *http.Transport, dialer *net.Dialer, resolve map[string]string) {
+	guarded := *dialer
+	guarded.Control = g.control
+	transport.DialContext = g.dialContext(resolvingDialer(&guarded, resolve), transport.DialContext)
+	transport.Proxy = g.proxy(transport.Proxy)
+}
+
+// control is a net.Dialer Control function that checks the resolved address
+// right before connecting
+func (g *addressGuard) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	return g.check("", addrPort.Addr())
+}
+
+// dialContext returns a dial function that connects to the proxy of the
+// request being sent with the direct dialer and to everything else with the
+// guarded one
+func (g *addressGuard) dialContext(guarded, direct func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if isProxy(ctx, addr) {
+			return direct(ctx, network, addr)
+		}
+		conn, err := guarded(ctx, network, addr)
+		var blocked *BlockedAddressError
+		if errors.As(err, &blocked) && blocked.Host == "" {
+			// Name the host that resolved to the blocked address
+			blocked.Host, _, _ = net.SplitHostPort(addr)
+		}
+		return conn, err
+	}
+}
+
+// proxy wraps a transport's proxy function. The target of a request sent
+// through a proxy is resolved and checked here, as the proxy connects to it
+// rather than the analyzer; the proxy itself may be internal. Host names
+// that resolve to any blocked address are refused.
+//
+// This check is weaker than the dial guard: the proxy resolves the host name
+// again, so a DNS server that answers with an allowed address here and a
+// blocked one to the proxy (DNS rebinding) gets around it. Only a proxy that
+// refuses internal addresses itself closes that gap.
+func (g *addressGuard) proxy(next func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := next(req)
+		if proxyURL == nil || err != nil {
+			return proxyURL, err
+		}
+		if err := g.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		if dial, ok := req.Context().Value(proxyDialKey{}).(*proxyDial); ok {
+			dial.mu.Lock()
+			dial.addr = proxyAddr(proxyURL)
+			dial.mu.Unlock()
+		}
+		return proxyURL, nil
+	}
+}
+
+// checkHost resolves a host name and checks all of its addresses
+func (g *addressGuard) checkHost(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return g.check(host, ip)
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if err := g.check(host, ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// proxyAddr returns the host and port the transport dials for a proxy
+func proxyAddr(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	var port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[strings.ToLower(u.Scheme)]
+	return net.JoinHostPort(u.Hostname(), port)
+}
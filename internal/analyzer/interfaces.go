@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net/url"
+	"time"
 
 	"golang.org/x/net/html"
 )
@@ -16,6 +17,7 @@ type PageAnalyzer interface {
 // LinkChecker defines the interface for checking link accessibility
 type LinkChecker interface {
 	CheckAccessibility(ctx context.Context, urlStr string) bool
+	CheckLink(ctx context.Context, urlStr string) LinkCheckResult
 	CheckWithRetry(ctx context.Context, urlStr string) bool
 }
 
@@ -27,21 +29,26 @@ type HTMLParser interface {
 	ExtractLinks(doc *html.Node, baseURL *url.URL) []LinkInfo
 	ExtractForms(doc *html.Node) []*html.Node
 	ExtractHTMLVersion(doc *html.Node) string
+	ExtractCanonical(doc *html.Node, baseURL *url.URL) string
 }
 
 // MetricsCollector defines the interface for collecting metrics
 type MetricsCollector interface {
 	RecordDuration(duration float64)
+	RecordStage(stage string, d time.Duration)
 	RecordResults(result *AnalysisResult)
+	RecordPhaseTimings(target string, timings PhaseTimings)
+	RecordLinkCheck(check LinkCheckResult)
 	RecordError(err error)
 	RecordRequest()
 }
 
-// Logger defines the interface for logging operations
+// Logger defines the interface for logging operations. The target URL is
+// taken from the context, which Analyze stamps with it.
 type Logger interface {
-	LogAnalysisStart(url string)
-	LogAnalysisError(err error, url string)
-	LogAnalysisComplete(url string, duration float64)
-	LogLinkCheck(url string, isAccessible bool)
-	LogDebug(msg string, args ...interface{})
+	LogAnalysisStart(ctx context.Context)
+	LogAnalysisError(ctx context.Context, err error)
+	LogAnalysisComplete(ctx context.Context, duration float64)
+	LogLinkCheck(ctx context.Context, url string, isAccessible bool)
+	LogDebug(ctx context.Context, msg string, args ...interface{})
 }
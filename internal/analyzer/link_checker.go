@@ -25,21 +25,35 @@ func NewDefaultLinkChecker(client *http.Client, log Logger, config *AnalyzerConf
 
 // CheckAccessibility checks if a link is accessible
 func (c *DefaultLinkChecker) CheckAccessibility(ctx context.Context, urlStr string) bool {
-	req, err := http.NewRequestWithContext(ctx, "HEAD", urlStr, nil)
+	return c.CheckLink(ctx, urlStr).Accessible
+}
+
+// CheckLink checks a link and reports its status code and request timings
+func (c *DefaultLinkChecker) CheckLink(ctx context.Context, urlStr string) LinkCheckResult {
+	result := LinkCheckResult{URL: urlStr}
+
+	traceCtx, tracer := newRequestTracer(ctx)
+	req, err := http.NewRequestWithContext(traceCtx, "HEAD", urlStr, nil)
 	if err != nil {
-		c.log.LogDebug("Failed to create request for link", "link", urlStr, "error", err)
-		return false
+		c.log.LogDebug(ctx, "Failed to create request for link", "link", urlStr, "error", err)
+		result.Err = err
+		return result
 	}
 
 	req.Header.Set("User-Agent", c.config.UserAgent)
 	resp, err := c.client.Do(req)
 	if err != nil {
-		c.log.LogDebug("Failed to check link", "link", urlStr, "error", err)
-		return false
+		c.log.LogDebug(ctx, "Failed to check link", "link", urlStr, "error", err)
+		result.Err = err
+		result.Timings = tracer.Timings(time.Time{})
+		return result
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode >= 200 && resp.StatusCode < 400
+	result.StatusCode = resp.StatusCode
+	result.Accessible = resp.StatusCode >= 200 && resp.StatusCode < 400
+	result.Timings = tracer.Timings(time.Now())
+	return result
 }
 
 // CheckWithRetry checks a link with retry logic
@@ -78,7 +92,7 @@ func (c *DefaultLinkChecker) CheckLinksConcurrently(ctx context.Context, links [
 			mu.Lock()
 			results[l.URL] = isAccessible
 			mu.Unlock()
-			c.log.LogLinkCheck(l.URL, isAccessible)
+			c.log.LogLinkCheck(ctx, l.URL, isAccessible)
 		}(link)
 	}
 
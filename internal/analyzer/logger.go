@@ -1,11 +1,14 @@
 package analyzer
 
 import (
+	"context"
 	"log/slog"
 	"time"
 )
 
-// AnalyzerLogger implements the Logger interface
+// AnalyzerLogger implements the Logger interface. Lines are logged with the
+// context of the analysis, so a handler that adds the context's attributes
+// stamps them with the request, job and target URL they belong to.
 type AnalyzerLogger struct {
 	log *slog.Logger
 }
@@ -16,38 +19,35 @@ func NewAnalyzerLogger(log *slog.Logger) *AnalyzerLogger {
 }
 
 // LogAnalysisStart logs the start of a page analysis
-func (l *AnalyzerLogger) LogAnalysisStart(url string) {
-	l.log.Info("starting analysis",
-		slog.String("url", url),
+func (l *AnalyzerLogger) LogAnalysisStart(ctx context.Context) {
+	l.log.InfoContext(ctx, "starting analysis",
 		slog.Time("start_time", time.Now()),
 	)
 }
 
 // LogAnalysisError logs an error during analysis
-func (l *AnalyzerLogger) LogAnalysisError(err error, url string) {
-	l.log.Error("analysis failed",
-		slog.String("url", url),
+func (l *AnalyzerLogger) LogAnalysisError(ctx context.Context, err error) {
+	l.log.ErrorContext(ctx, "analysis failed",
 		slog.String("error", err.Error()),
 	)
 }
 
 // LogAnalysisComplete logs the completion of analysis
-func (l *AnalyzerLogger) LogAnalysisComplete(url string, duration float64) {
-	l.log.Info("analysis complete",
-		slog.String("url", url),
+func (l *AnalyzerLogger) LogAnalysisComplete(ctx context.Context, duration float64) {
+	l.log.InfoContext(ctx, "analysis complete",
 		slog.Float64("duration_seconds", duration),
 	)
 }
 
 // LogLinkCheck logs the result of a link check
-func (l *AnalyzerLogger) LogLinkCheck(url string, isAccessible bool) {
-	l.log.Debug("link check result",
-		slog.String("url", url),
+func (l *AnalyzerLogger) LogLinkCheck(ctx context.Context, url string, isAccessible bool) {
+	l.log.DebugContext(ctx, "link check result",
+		slog.String("link", url),
 		slog.Bool("is_accessible", isAccessible),
 	)
 }
 
 // LogDebug logs a debug message
-func (l *AnalyzerLogger) LogDebug(msg string, args ...interface{}) {
-	l.log.Debug(msg, args...)
+func (l *AnalyzerLogger) LogDebug(ctx context.Context, msg string, args ...interface{}) {
+	l.log.DebugContext(ctx, msg, args...)
 }
@@ -1,24 +1,58 @@
 package analyzer
 
 import (
+	"errors"
+	"strconv"
+	"time"
+
 	"home24/internal/metrics"
 )
 
+// Stages of an analysis recorded by RecordStage
+const (
+	StageFetch      = "fetch"
+	StageParse      = "parse"
+	StageCheckLinks = "check_links"
+)
+
 // PrometheusMetricsCollector implements the MetricsCollector interface
 type PrometheusMetricsCollector struct {
-	config AnalyzerConfig
+	metrics *metrics.Metrics
 }
 
 // NewPrometheusMetricsCollector creates a new PrometheusMetricsCollector
-func NewPrometheusMetricsCollector(config AnalyzerConfig) *PrometheusMetricsCollector {
+// recording to the given metrics
+func NewPrometheusMetricsCollector(m *metrics.Metrics) *PrometheusMetricsCollector {
 	return &PrometheusMetricsCollector{
-		config: config,
+		metrics: m,
 	}
 }
 
 // RecordDuration records the duration of an analysis
 func (m *PrometheusMetricsCollector) RecordDuration(duration float64) {
-	metrics.AnalysisDuration.Observe(duration)
+	m.metrics.AnalysisDuration.Observe(duration)
+}
+
+// RecordStage records how long a stage of an analysis took
+func (m *PrometheusMetricsCollector) RecordStage(stage string, d time.Duration) {
+	m.metrics.StageDuration.WithLabelValues(stage).Observe(d.Seconds())
+}
+
+// RecordPhaseTimings records request phase timings for the given target
+// ("page" or "link"). Phases that did not happen are skipped so reused
+// connections don't drag the DNS and connect histograms towards zero.
+func (m *PrometheusMetricsCollector) RecordPhaseTimings(target string, timings PhaseTimings) {
+	for phase, d := range timings.Phases() {
+		if d <= 0 {
+			continue
+		}
+		m.metrics.PhaseDuration.WithLabelValues(target, phase).Observe(d.Seconds())
+	}
+}
+
+// RecordLinkCheck records the status class of a checked link
+func (m *PrometheusMetricsCollector) RecordLinkCheck(check LinkCheckResult) {
+	m.metrics.LinkChecks.WithLabelValues(statusClass(check)).Inc()
 }
 
 // RecordResults records the results of an analysis
@@ -26,32 +60,46 @@ func (m *PrometheusMetricsCollector) RecordResults(result *AnalysisResult) {
 	// Record link counts
 	for _, link := range result.Links {
 		if link.IsInternal {
-			metrics.LinkCounts.WithLabelValues("internal").Inc()
+			m.metrics.Links.WithLabelValues("internal").Inc()
 		} else {
-			metrics.LinkCounts.WithLabelValues("external").Inc()
+			m.metrics.Links.WithLabelValues("external").Inc()
 		}
 	}
 
 	// Record heading counts
 	for level, count := range result.Headings {
-		metrics.HeadingCounts.WithLabelValues(level).Set(float64(count))
+		m.metrics.Headings.WithLabelValues(level).Add(float64(count))
 	}
 
 	// Record login form count
 	if result.HasLoginForm {
-		metrics.LoginFormCount.Inc()
+		m.metrics.LoginForms.Inc()
 	}
 
 	// Record HTML version
-	metrics.HTMLVersionCount.WithLabelValues(result.HTMLVersion).Inc()
+	m.metrics.HTMLVersions.WithLabelValues(result.HTMLVersion).Inc()
 }
 
-// RecordError records an error during analysis
+// RecordError records a failed analysis by its error code
 func (m *PrometheusMetricsCollector) RecordError(err error) {
-	metrics.AnalysisErrors.Inc()
+	code := "UNKNOWN"
+	var analysisErr *AnalysisError
+	if errors.As(err, &analysisErr) {
+		code = analysisErr.Code
+	}
+	m.metrics.AnalysisErrors.WithLabelValues(code).Inc()
 }
 
 // RecordRequest records a new analysis request
 func (m *PrometheusMetricsCollector) RecordRequest() {
-	metrics.AnalysisRequests.Inc()
+	m.metrics.AnalysisRequests.Inc()
+}
+
+// statusClass returns the class of a link check's status code, e.g. 4xx, or
+// "error" when no response was received
+func statusClass(check LinkCheckResult) string {
+	if check.Err != nil || check.StatusCode < 100 || check.StatusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(check.StatusCode/100) + "xx"
 }
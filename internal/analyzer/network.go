@@ -0,0 +1,200 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// NetworkConfig holds the outbound network options of the HTTP client
+type NetworkConfig struct {
+	// ProxyURL routes all requests through an HTTP(S) proxy. When empty the
+	// standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables are honoured.
+	// The proxy resolves targets itself, so it should refuse internal
+	// addresses as well; the analyzer can only check its own lookup.
+	ProxyURL string `yaml:"proxyURL"`
+
+	// Resolve pins host names to IP addresses like curl's --resolve. Keys are
+	// "host:port" or "host" for every port; values are IP addresses.
+	Resolve map[string]string `yaml:"resolve"`
+
+	// CACertFiles are PEM bundles trusted in addition to the system roots
+	CACertFiles []string `yaml:"caCertFiles"`
+
+	// InsecureSkipVerifyHosts disables TLS certificate verification for the
+	// listed host names only. Every other host is still fully verified.
+	InsecureSkipVerifyHosts []string `yaml:"insecureSkipVerifyHosts"`
+
+	// AllowedNetworks are CIDR ranges or addresses the analyzer may connect
+	// to although they are private, loopback or link-local, e.g. staging
+	// networks. Connections to those ranges are refused otherwise.
+	AllowedNetworks []string `yaml:"allowedNetworks"`
+
+	// AllowPrivateNetworks turns the check of the dialed addresses off. It
+	// is meant for local development only.
+	AllowPrivateNetworks bool `yaml:"allowPrivateNetworks"`
+}
+
+// Validate checks the network options without building a transport
+func (c NetworkConfig) Validate() error {
+	var errs []error
+
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("proxyURL: %w", err))
+		} else if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			errs = append(errs, fmt.Errorf("proxyURL: unsupported scheme %q", u.Scheme))
+		}
+	}
+
+	for host, ip := range c.Resolve {
+		if host == "" {
+			errs = append(errs, errors.New("resolve: empty host"))
+		}
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("resolve: %q is not an IP address for %s", ip, host))
+		}
+	}
+
+	if _, err := parseNetworks(c.AllowedNetworks); err != nil {
+		errs = append(errs, fmt.Errorf("allowedNetworks: %w", err))
+	}
+
+	for _, file := range c.CACertFiles {
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, fmt.Errorf("caCertFiles: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// newTransport builds the base HTTP transport for the network options
+func newTransport(c NetworkConfig) (*http.Transport, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.ProxyURL != "" {
+		proxyURL, _ := url.Parse(c.ProxyURL)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = resolvingDialer(dialer, c.Resolve)
+
+	// Refuse connections to internal addresses, so pages, their redirects
+	// and their links can't make the analyzer reach internal services
+	if !c.AllowPrivateNetworks {
+		allowed, _ := parseNetworks(c.AllowedNetworks)
+		guard := &addressGuard{allowed: allowed}
+		guard.guardTransport(transport, dialer, c.Resolve)
+	}
+
+	if len(c.CACertFiles) == 0 && len(c.InsecureSkipVerifyHosts) == 0 {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if len(c.CACertFiles) > 0 {
+		roots, err := loadRootCAs(c.CACertFiles)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if len(c.InsecureSkipVerifyHosts) > 0 {
+		// Go only offers an all-or-nothing switch, so verification is turned
+		// off and redone by hand for every host that isn't listed
+		insecure := make(map[string]bool)
+		for _, host := range c.InsecureSkipVerifyHosts {
+			insecure[strings.ToLower(host)] = true
+		}
+		roots := tlsConfig.RootCAs
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if insecure[strings.ToLower(cs.ServerName)] {
+				return nil
+			}
+			return verifyPeer(cs, roots)
+		}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+// resolvingDialer dials the pinned address for overridden hosts
+func resolvingDialer(dialer *net.Dialer, overrides map[string]string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if len(overrides) == 0 {
+		return dialer.DialContext
+	}
+
+	pinned := make(map[string]string, len(overrides))
+	for host, ip := range overrides {
+		pinned[strings.ToLower(host)] = ip
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = strings.ToLower(host)
+		if ip, ok := pinned[net.JoinHostPort(host, port)]; ok {
+			addr = net.JoinHostPort(ip, port)
+		} else if ip, ok := pinned[host]; ok {
+			addr = net.JoinHostPort(ip, port)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// loadRootCAs returns the system roots extended with the given PEM bundles
+func loadRootCAs(files []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// verifyPeer performs the certificate verification crypto/tls would do
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: server presented no certificates")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
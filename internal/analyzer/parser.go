@@ -135,3 +135,36 @@ func (p *DefaultHTMLParser) ExtractHTMLVersion(doc *html.Node) string {
 	findDoctype(doc)
 	return version
 }
+
+// ExtractCanonical extracts the canonical URL from <link rel="canonical">,
+// resolved against the page URL
+func (p *DefaultHTMLParser) ExtractCanonical(doc *html.Node, baseURL *url.URL) string {
+	var canonical string
+	var findCanonical func(*html.Node)
+	findCanonical = func(n *html.Node) {
+		if canonical != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, href string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				}
+			}
+			if rel == "canonical" && href != "" {
+				if canonicalURL, err := baseURL.Parse(href); err == nil {
+					canonical = canonicalURL.String()
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findCanonical(c)
+		}
+	}
+	findCanonical(doc)
+	return canonical
+}
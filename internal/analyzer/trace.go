@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Request phase names used for timings and metrics labels
+const (
+	PhaseDNS       = "dns"
+	PhaseConnect   = "connect"
+	PhaseTLS       = "tls"
+	PhaseFirstByte = "ttfb"
+	PhaseDownload  = "download"
+)
+
+// PhaseTimings holds the time spent in each phase of a single HTTP request.
+// Phases that did not happen (for example DNS and connect on a reused
+// connection, or TLS on plain HTTP) are left at zero.
+type PhaseTimings struct {
+	DNSLookup       time.Duration `json:"dns_lookup_ns"`
+	TCPConnect      time.Duration `json:"tcp_connect_ns"`
+	TLSHandshake    time.Duration `json:"tls_handshake_ns"`
+	TimeToFirstByte time.Duration `json:"time_to_first_byte_ns"`
+	Download        time.Duration `json:"download_ns"`
+	Total           time.Duration `json:"total_ns"`
+	ReusedConn      bool          `json:"reused_conn"`
+}
+
+// Phases returns the timings keyed by phase name
+func (t PhaseTimings) Phases() map[string]time.Duration {
+	return map[string]time.Duration{
+		PhaseDNS:       t.DNSLookup,
+		PhaseConnect:   t.TCPConnect,
+		PhaseTLS:       t.TLSHandshake,
+		PhaseFirstByte: t.TimeToFirstByte,
+		PhaseDownload:  t.Download,
+	}
+}
+
+// requestTracer records phase timestamps through an httptrace.ClientTrace
+type requestTracer struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// newRequestTracer returns a tracer and a context that reports to it
+func newRequestTracer(ctx context.Context) (context.Context, *requestTracer) {
+	t := &requestTracer{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mark(&t.start)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mark(&t.dnsDone)
+		},
+		ConnectStart: func(string, string) {
+			t.markOnce(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.mark(&t.connectDone)
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mark(&t.tlsDone)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *requestTracer) mark(ts *time.Time) {
+	t.mu.Lock()
+	*ts = time.Now()
+	t.mu.Unlock()
+}
+
+// markOnce keeps the first timestamp, since dual-stack dialing may start
+// several connection attempts
+func (t *requestTracer) markOnce(ts *time.Time) {
+	t.mu.Lock()
+	if ts.IsZero() {
+		*ts = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// Timings computes the phase durations. end is the moment the response body
+// was fully consumed; a zero end means the body was not read.
+func (t *requestTracer) Timings(end time.Time) PhaseTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := PhaseTimings{
+		DNSLookup:    between(t.dnsStart, t.dnsDone),
+		TCPConnect:   between(t.connectStart, t.connectDone),
+		TLSHandshake: between(t.tlsStart, t.tlsDone),
+		ReusedConn:   t.reused,
+	}
+
+	// Time to first byte is measured from the moment the request was written
+	// so that it reflects server processing rather than connection setup
+	if !t.wroteRequest.IsZero() {
+		timings.TimeToFirstByte = between(t.wroteRequest, t.firstByte)
+	} else {
+		timings.TimeToFirstByte = between(t.start, t.firstByte)
+	}
+
+	if !end.IsZero() {
+		timings.Download = between(t.firstByte, end)
+		timings.Total = between(t.start, end)
+	} else {
+		timings.Total = between(t.start, t.firstByte)
+	}
+
+	return timings
+}
+
+// between returns the duration from start to end, or zero when either is unset
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
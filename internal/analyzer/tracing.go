@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the tracer the analyzer creates its spans with
+const tracerName = "home24/internal/analyzer"
+
+// Span attributes specific to the analyzer
+const (
+	attrErrorCode       = attribute.Key("analyzer.error_code")
+	attrLinks           = attribute.Key("analyzer.links")
+	attrAccessibleLinks = attribute.Key("analyzer.accessible_links")
+	attrHTMLVersion     = attribute.Key("analyzer.html_version")
+	attrLoginForm       = attribute.Key("analyzer.login_form")
+	attrLinkAccessible  = attribute.Key("analyzer.link.accessible")
+	attrLinkStatusClass = attribute.Key("analyzer.link.status_class")
+)
+
+// endSpan ends a span, marking it as failed if err is set
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		var analysisErr *AnalysisError
+		if errors.As(err, &analysisErr) {
+			span.SetAttributes(attrErrorCode.String(analysisErr.Code))
+		}
+	}
+	span.End()
+}
+
+// endLinkSpan records the outcome of a link check on its span and ends it.
+// Links that aren't accessible mark the span as failed.
+func endLinkSpan(span trace.Span, check LinkCheckResult) {
+	span.SetAttributes(
+		attrLinkAccessible.Bool(check.Accessible),
+		attrLinkStatusClass.String(statusClass(check)),
+	)
+	if check.StatusCode != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(check.StatusCode))
+	}
+	switch {
+	case check.Err != nil:
+		span.RecordError(check.Err)
+		span.SetStatus(codes.Error, check.Err.Error())
+	case !check.Accessible:
+		span.SetStatus(codes.Error, "link is not accessible")
+	}
+	span.End()
+}
@@ -1,18 +1,54 @@
 package analyzer
 
+import "time"
+
 // LinkInfo represents information about a link found in the page
 type LinkInfo struct {
+	URL        string       `json:"url"`
+	IsInternal bool         `json:"is_internal"`
+	Accessible bool         `json:"accessible"`
+	StatusCode int          `json:"status_code,omitempty"`
+	Timings    PhaseTimings `json:"timings"`
+}
+
+// LinkCheckResult represents the outcome of checking a single link
+type LinkCheckResult struct {
 	URL        string
-	IsInternal bool
+	Accessible bool
+	StatusCode int
+	Timings    PhaseTimings
+	Err        error
 }
 
 // AnalysisResult represents the complete analysis of a webpage
 type AnalysisResult struct {
-	URL             string
-	Title           string
-	Headings        map[string]int
-	Links           []LinkInfo
-	AccessibleLinks int
-	HasLoginForm    bool
-	HTMLVersion     string
+	URL             string         `json:"url"`
+	FinalURL        string         `json:"final_url"`
+	StatusCode      int            `json:"status_code"`
+	Canonical       string         `json:"canonical,omitempty"`
+	Title           string         `json:"title"`
+	Headings        map[string]int `json:"headings"`
+	Links           []LinkInfo     `json:"links"`
+	AccessibleLinks int            `json:"accessible_links"`
+	HasLoginForm    bool           `json:"has_login_form"`
+	HTMLVersion     string         `json:"html_version"`
+	Timings         PhaseTimings   `json:"timings"`
+	Duration        time.Duration  `json:"duration_ns"`
+
+	// Partial is set when the analysis was stopped while checking links;
+	// only the links checked until then are listed
+	Partial bool `json:"partial,omitempty"`
+}
+
+// clone returns a copy of the result that doesn't share the links slice or
+// headings map, so it can be handed to event handlers while the analysis
+// keeps updating the original
+func (r *AnalysisResult) clone() *AnalysisResult {
+	c := *r
+	c.Links = append([]LinkInfo{}, r.Links...)
+	c.Headings = make(map[string]int, len(r.Headings))
+	for level, count := range r.Headings {
+		c.Headings[level] = count
+	}
+	return &c
 }
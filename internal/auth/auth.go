@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The scopes a key can have. A key with the admin scope may do everything.
+const (
+	ScopeAnalyze     = "analyze"
+	ScopeCrawl       = "crawl"
+	ScopeReadHistory = "read-history"
+	ScopeAdmin       = "admin"
+)
+
+// Scopes lists every scope
+var Scopes = []string{ScopeAnalyze, ScopeCrawl, ScopeReadHistory, ScopeAdmin}
+
+// The header a client can send its API key in, instead of an
+// "Authorization: Bearer" header
+const APIKeyHeader = "X-API-Key"
+
+// The prefix of generated keys, which makes them easy to spot in leaks
+const keyPrefix = "wpa_"
+
+var (
+	// ErrMissingKey is returned when a request has no API key
+	ErrMissingKey = errors.New("an API key is required")
+
+	// ErrInvalidKey is returned for keys that don't exist
+	ErrInvalidKey = errors.New("invalid API key")
+
+	// ErrKeyNotFound is returned by key stores for keys that don't exist
+	ErrKeyNotFound = errors.New("API key not found")
+
+	// ErrNoKeyStore is returned when keys are managed without a key store
+	ErrNoKeyStore = errors.New("API keys can't be stored")
+)
+
+// KeyConfig is an API key defined in the configuration. Either the key
+// itself or its hex SHA-256 hash is given; the hash keeps the key out of
+// the file.
+type KeyConfig struct {
+	Name   string   `yaml:"name"`
+	Key    string   `yaml:"key"`
+	SHA256 string   `yaml:"sha256"`
+	Scopes []string `yaml:"scopes"`
+}
+
+// Config holds the authentication settings
+type Config struct {
+	// Enabled requires an API key for every route except the health
+	// checks, the metrics and the static pages
+	Enabled bool        `yaml:"enabled"`
+	Keys    []KeyConfig `yaml:"keys"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Validate checks the configured keys
+func (c Config) Validate() error {
+	var errs []error
+	var names = make(map[string]bool)
+	for i, key := range c.Keys {
+		if key.Name == "" {
+			errs = append(errs, fmt.Errorf("key %d: name is required", i+1))
+		} else if names[key.Name] {
+			errs = append(errs, fmt.Errorf("key %q: name is used more than once", key.Name))
+		}
+		names[key.Name] = true
+
+		switch {
+		case (key.Key == "") == (key.SHA256 == ""):
+			errs = append(errs, fmt.Errorf("key %q: exactly one of key and sha256 is required", key.Name))
+		case key.SHA256 != "":
+			if decoded, err := hex.DecodeString(key.SHA256); err != nil || len(decoded) != sha256.Size {
+				errs = append(errs, fmt.Errorf("key %q: sha256 must be 64 hex characters", key.Name))
+			}
+		}
+		if err := ValidateScopes(key.Scopes); err != nil {
+			errs = append(errs, fmt.Errorf("key %q: %w", key.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateScopes checks that scopes are given and known
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope %q, use %s", scope, strings.Join(Scopes, ", "))
+		}
+	}
+	return nil
+}
+
+// StoredKey is an API key kept in a key store. Only the hash of the key is
+// stored; the key itself is shown once when it is created.
+type StoredKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"-"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// KeyStore keeps the API keys created at runtime
+type KeyStore interface {
+	// SaveKey stores a key and assigns its ID and creation time
+	SaveKey(ctx context.Context, key *StoredKey) error
+
+	// FindKey returns the key with the given hash or ErrKeyNotFound
+	FindKey(ctx context.Context, hash string) (*StoredKey, error)
+
+	// ListKeys returns every stored key, oldest first
+	ListKeys(ctx context.Context) ([]StoredKey, error)
+
+	// DeleteKey removes a key or returns ErrKeyNotFound
+	DeleteKey(ctx context.Context, id string) error
+}
+
+// Identity is the key a request was made with
+type Identity struct {
+	// Name is the name of the key, which analyses are attributed to
+	Name string
+
+	// KeyID is the ID of a stored key; it is empty for configured keys
+	KeyID string
+
+	Scopes []string
+}
+
+// Allows reports whether the key has the scope
+func (i *Identity) Allows(scope string) bool {
+	return slices.Contains(i.Scopes, scope) || slices.Contains(i.Scopes, ScopeAdmin)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity of the caller
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity of the caller, or nil when the request
+// wasn't authenticated
+func FromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// HashKey returns the hex SHA-256 hash a key is stored and looked up by.
+// Generated keys are random enough that a plain hash is safe.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random key
+func GenerateKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// KeyFromRequest returns the API key sent with a request: in the X-API-Key
+// header, as a bearer token, or as the password of basic authentication so
+// browsers can ask for it
+func KeyFromRequest(req *http.Request) string {
+	if key := req.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if _, password, ok := req.BasicAuth(); ok {
+		return password
+	}
+	return ""
+}
+
+// Authenticator checks API keys against the configured keys and the keys
+// in the key store
+type Authenticator struct {
+	configured map[string]*Identity
+	keys       KeyStore
+}
+
+// NewAuthenticator creates an authenticator for a validated configuration.
+// The key store may be nil, in which case only configured keys are known.
+func NewAuthenticator(config Config, keys KeyStore) *Authenticator {
+	var configured = make(map[string]*Identity, len(config.Keys))
+	for _, key := range config.Keys {
+		var hash = strings.ToLower(key.SHA256)
+		if key.Key != "" {
+			hash = HashKey(key.Key)
+		}
+		configured[hash] = &Identity{Name: key.Name, Scopes: key.Scopes}
+	}
+	return &Authenticator{configured: configured, keys: keys}
+}
+
+// Authenticate returns the identity of a key. It returns ErrMissingKey or
+// ErrInvalidKey if the key can't be used, or the error of the key store.
+func (a *Authenticator) Authenticate(ctx context.Context, key string) (*Identity, error) {
+	if key == "" {
+		return nil, ErrMissingKey
+	}
+	var hash = HashKey(key)
+	if identity, ok := a.configured[hash]; ok {
+		return identity, nil
+	}
+	if a.keys == nil {
+		return nil, ErrInvalidKey
+	}
+
+	stored, err := a.keys.FindKey(ctx, hash)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up API key: %w", err)
+	}
+	return &Identity{Name: stored.Name, KeyID: stored.ID, Scopes: stored.Scopes}, nil
+}
+
+// Recheck returns the current identity of a key that was authenticated
+// before, e.g. by a job saved at shutdown, with its current scopes. It
+// returns ErrInvalidKey if the key was deleted or removed from the
+// configuration since.
+func (a *Authenticator) Recheck(ctx context.Context, identity *Identity) (*Identity, error) {
+	if identity.KeyID == "" {
+		for _, configured := range a.configured {
+			if configured.Name == identity.Name {
+				return configured, nil
+			}
+		}
+		return nil, ErrInvalidKey
+	}
+	if a.keys == nil {
+		return nil, ErrInvalidKey
+	}
+
+	stored, err := a.keys.ListKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up API key: %w", err)
+	}
+	for _, key := range stored {
+		if key.ID == identity.KeyID {
+			return &Identity{Name: key.Name, KeyID: key.ID, Scopes: key.Scopes}, nil
+		}
+	}
+	return nil, ErrInvalidKey
+}
+
+// CanStoreKeys reports whether keys can be created at runtime
+func (a *Authenticator) CanStoreKeys() bool {
+	return a.keys != nil
+}
+
+// CreateKey creates and stores a new key. The key is returned once and
+// can't be recovered afterwards.
+func (a *Authenticator) CreateKey(ctx context.Context, name string, scopes []string) (string, *StoredKey, error) {
+	if a.keys == nil {
+		return "", nil, ErrNoKeyStore
+	}
+	if strings.TrimSpace(name) == "" {
+		return "", nil, errors.New("name is required")
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+
+	// Analyses are attributed by name, so names must be unique
+	existing, err := a.ListKeys(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, key := range existing {
+		if key.Name == name {
+			return "", nil, fmt.Errorf("a key named %q already exists", name)
+		}
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		return "", nil, err
+	}
+	stored := &StoredKey{Name: name, Hash: HashKey(key), Scopes: scopes}
+	if err := a.keys.SaveKey(ctx, stored); err != nil {
+		return "", nil, err
+	}
+	return key, stored, nil
+}
+
+// ListKeys returns the configured keys, which have no ID, followed by the
+// stored keys
+func (a *Authenticator) ListKeys(ctx context.Context) ([]StoredKey, error) {
+	keys := []StoredKey{}
+	for _, identity := range a.configured {
+		keys = append(keys, StoredKey{Name: identity.Name, Scopes: identity.Scopes})
+	}
+	slices.SortFunc(keys, func(a, b StoredKey) int { return strings.Compare(a.Name, b.Name) })
+
+	if a.keys != nil {
+		stored, err := a.keys.ListKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, stored...)
+	}
+	return keys, nil
+}
+
+// DeleteKey revokes a stored key. Configured keys are removed from the
+// configuration instead.
+func (a *Authenticator) DeleteKey(ctx context.Context, id string) error {
+	if a.keys == nil {
+		return ErrNoKeyStore
+	}
+	return a.keys.DeleteKey(ctx, id)
+}
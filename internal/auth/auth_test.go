@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeKeyStore keeps keys in a map by hash
+type fakeKeyStore struct {
+	keys map[string]*StoredKey
+	err  error
+}
+
+func (f *fakeKeyStore) SaveKey(ctx context.Context, key *StoredKey) error {
+	key.ID = "id-" + key.Name
+	f.keys[key.Hash] = key
+	return nil
+}
+
+func (f *fakeKeyStore) FindKey(ctx context.Context, hash string) (*StoredKey, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if key, ok := f.keys[hash]; ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (f *fakeKeyStore) ListKeys(ctx context.Context) ([]StoredKey, error) {
+	var keys []StoredKey
+	for _, key := range f.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (f *fakeKeyStore) DeleteKey(ctx context.Context, id string) error {
+	for hash, key := range f.keys {
+		if key.ID == id {
+			delete(f.keys, hash)
+			return nil
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// Test every problem of the configured keys is reported
+func TestValidate(t *testing.T) {
+	var config = Config{Keys: []KeyConfig{
+		{Name: "ci", Key: "secret", Scopes: []string{ScopeAnalyze}},
+		{Name: "ci", SHA256: HashKey("other"), Scopes: []string{ScopeCrawl}},
+		{Name: "both", Key: "a", SHA256: HashKey("a"), Scopes: []string{ScopeAdmin}},
+		{Name: "short", SHA256: "abc", Scopes: []string{ScopeAdmin}},
+		{Key: "nameless", Scopes: []string{"write"}},
+	}}
+	var err = config.Validate()
+	if err == nil {
+		t.Fatal("Expected the configuration to be invalid")
+	}
+	for _, expected := range []string{
+		`key "ci": name is used more than once`,
+		`key "both": exactly one of key and sha256`,
+		`key "short": sha256 must be 64 hex characters`,
+		"key 5: name is required",
+		`unknown scope "write"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in %q", expected, err)
+		}
+	}
+
+	if err := (Config{Keys: config.Keys[:1]}).Validate(); err != nil {
+		t.Errorf("Expected a valid configuration, got %v", err)
+	}
+}
+
+// Test configured and stored keys are recognized with their scopes
+func TestAuthenticate(t *testing.T) {
+	var ctx = context.Background()
+	var store = &fakeKeyStore{keys: make(map[string]*StoredKey)}
+	var authenticator = NewAuthenticator(Config{Keys: []KeyConfig{
+		{Name: "ci", Key: "plain", Scopes: []string{ScopeAnalyze}},
+		{Name: "ops", SHA256: strings.ToUpper(HashKey("hashed")), Scopes: []string{ScopeAdmin}},
+	}}, store)
+
+	var identity, err = authenticator.Authenticate(ctx, "plain")
+	if err != nil || identity.Name != "ci" || !identity.Allows(ScopeAnalyze) || identity.Allows(ScopeCrawl) {
+		t.Errorf("Unexpected identity %+v, %v", identity, err)
+	}
+	identity, err = authenticator.Authenticate(ctx, "hashed")
+	if err != nil || identity.Name != "ops" || !identity.Allows(ScopeReadHistory) {
+		t.Errorf("Expected the admin key to allow every scope, got %+v, %v", identity, err)
+	}
+	if _, err := authenticator.Authenticate(ctx, ""); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("Expected ErrMissingKey, got %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, "wrong"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+
+	// Keys created at runtime are stored hashed and returned only once
+	var key, stored, createErr = authenticator.CreateKey(ctx, "reports", []string{ScopeReadHistory})
+	if createErr != nil {
+		t.Fatalf("Error creating key: %v", createErr)
+	}
+	if !strings.HasPrefix(key, keyPrefix) || stored.Hash != HashKey(key) || strings.Contains(stored.Hash, key) {
+		t.Errorf("Expected a prefixed key stored by its hash, got %q and %+v", key, stored)
+	}
+	identity, err = authenticator.Authenticate(ctx, key)
+	if err != nil || identity.Name != "reports" || identity.KeyID != "id-reports" {
+		t.Errorf("Unexpected identity %+v, %v", identity, err)
+	}
+	if _, _, err := authenticator.CreateKey(ctx, "ci", []string{ScopeAnalyze}); err == nil {
+		t.Error("Expected a key with the name of a configured key to be rejected")
+	}
+	if _, _, err := authenticator.CreateKey(ctx, "nothing", nil); err == nil {
+		t.Error("Expected a key without scopes to be rejected")
+	}
+
+	// Identities saved earlier are checked against the current keys
+	if rechecked, err := authenticator.Recheck(ctx, identity); err != nil || rechecked.KeyID != "id-reports" {
+		t.Errorf("Expected the stored key to be found, got %+v, %v", rechecked, err)
+	}
+	if rechecked, err := authenticator.Recheck(ctx, &Identity{Name: "ci"}); err != nil || !rechecked.Allows(ScopeAnalyze) {
+		t.Errorf("Expected the configured key to be found, got %+v, %v", rechecked, err)
+	}
+	if _, err := authenticator.Recheck(ctx, &Identity{Name: "removed"}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected a removed configured key to be invalid, got %v", err)
+	}
+
+	if err := authenticator.DeleteKey(ctx, "id-reports"); err != nil {
+		t.Fatalf("Error deleting key: %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected the revoked key to be invalid, got %v", err)
+	}
+	if _, err := authenticator.Recheck(ctx, identity); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected the revoked key to be invalid when rechecked, got %v", err)
+	}
+
+	// Errors of the store aren't mistaken for invalid keys
+	store.err = errors.New("database is locked")
+	if _, err := authenticator.Authenticate(ctx, "unknown"); err == nil || errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected the store error, got %v", err)
+	}
+}
+
+// Test the key is taken from the header, a bearer token or basic auth
+func TestKeyFromRequest(t *testing.T) {
+	var req = httptest.NewRequest("GET", "/", nil)
+	if key := KeyFromRequest(req); key != "" {
+		t.Errorf("Expected no key, got %q", key)
+	}
+
+	req.SetBasicAuth("anyone", "from-basic")
+	if key := KeyFromRequest(req); key != "from-basic" {
+		t.Errorf("Expected the basic auth password, got %q", key)
+	}
+	req.Header.Set("Authorization", "Bearer from-bearer")
+	if key := KeyFromRequest(req); key != "from-bearer" {
+		t.Errorf("Expected the bearer token, got %q", key)
+	}
+	req.Header.Set(APIKeyHeader, "from-header")
+	if key := KeyFromRequest(req); key != "from-header" {
+		t.Errorf("Expected the header, got %q", key)
+	}
+}
@@ -0,0 +1,229 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/jobs"
+)
+
+// The number of pages listed as worst offenders
+const worstOffendersLimit = 10
+
+// Config holds the batch analysis settings
+type Config struct {
+	Concurrency int `yaml:"concurrency"`
+	MaxURLs     int `yaml:"maxURLs"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Concurrency: 5,
+		MaxURLs:     500,
+	}
+}
+
+// Error describes why a page could not be analyzed. StatusCode is the
+// status the page responded with if the analysis failed because of it.
+type Error struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// PageReport holds the outcome of analyzing one page of the batch
+type PageReport struct {
+	URL         string                   `json:"url"`
+	BrokenLinks int                      `json:"broken_links"`
+	Result      *analyzer.AnalysisResult `json:"result,omitempty"`
+	Error       *Error                   `json:"error,omitempty"`
+}
+
+// PageSummary identifies a page in the aggregated sections of the report
+type PageSummary struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	TotalLinks  int    `json:"total_links"`
+	BrokenLinks int    `json:"broken_links"`
+}
+
+// Report aggregates the analyses of a batch
+type Report struct {
+	Total          int            `json:"total"`
+	Succeeded      int            `json:"succeeded"`
+	Failed         int            `json:"failed"`
+	TotalLinks     int            `json:"total_links"`
+	BrokenLinks    int            `json:"broken_links"`
+	WorstOffenders []PageSummary  `json:"worst_offenders"`
+	LoginFormPages []string       `json:"login_form_pages"`
+	HTMLVersions   map[string]int `json:"html_versions"`
+	Pages          []PageReport   `json:"pages"`
+	Duration       time.Duration  `json:"duration_ns"`
+}
+
+// ParseURLList reads one URL per line. Blank lines and lines starting with
+// "#" are skipped, duplicates are dropped and every URL must be an absolute
+// http or https URL.
+func ParseURLList(r io.Reader) ([]string, error) {
+	var urls []string
+	var errs []error
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("line %d: invalid URL %q", lineNumber, line))
+			continue
+		}
+		if !seen[line] {
+			seen[line] = true
+			urls = append(urls, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return urls, nil
+}
+
+// Run analyzes the URLs with at most concurrency analyses in flight and
+// aggregates the results. Page progress is reported to the job running
+// with the context, if any.
+func Run(ctx context.Context, a analyzer.PageAnalyzer, urls []string, concurrency int) *Report {
+	start := time.Now()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	pages := make([]PageReport, len(urls))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	semaphore := make(chan struct{}, concurrency)
+
+	jobs.ReportPages(ctx, 0, len(urls))
+
+	// Per-link events of the individual analyses would interleave, so
+	// progress is reported per page instead
+	pageCtx := analyzer.WithEventHandler(ctx, nil)
+
+	for i, u := range urls {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(urls); j++ {
+				pages[j] = PageReport{URL: urls[j], Error: &Error{Code: analyzer.ErrCanceled, Message: "batch canceled"}}
+			}
+			wg.Wait()
+			return aggregate(pages, time.Since(start))
+		}
+
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			pages[i] = analyzePage(pageCtx, a, u)
+
+			mu.Lock()
+			done++
+			jobs.ReportPages(ctx, done, len(urls))
+			mu.Unlock()
+		}(i, u)
+	}
+
+	wg.Wait()
+	return aggregate(pages, time.Since(start))
+}
+
+// analyzePage runs a single analysis of the batch
+func analyzePage(ctx context.Context, a analyzer.PageAnalyzer, u string) PageReport {
+	result, err := a.Analyze(ctx, u)
+	if err != nil {
+		var analysisErr *analyzer.AnalysisError
+		if errors.As(err, &analysisErr) {
+			report := PageReport{URL: u, Error: &Error{Code: analysisErr.Code, Message: analysisErr.Error()}}
+			var statusErr *analyzer.StatusError
+			if errors.As(err, &statusErr) {
+				report.Error.StatusCode = statusErr.StatusCode
+			}
+			return report
+		}
+		return PageReport{URL: u, Error: &Error{Code: "ANALYSIS_FAILED", Message: err.Error()}}
+	}
+
+	broken := 0
+	for _, link := range result.Links {
+		if !link.Accessible {
+			broken++
+		}
+	}
+	return PageReport{URL: u, BrokenLinks: broken, Result: result}
+}
+
+// aggregate builds the report from the page reports
+func aggregate(pages []PageReport, duration time.Duration) *Report {
+	report := &Report{
+		Total:          len(pages),
+		HTMLVersions:   make(map[string]int),
+		LoginFormPages: []string{},
+		WorstOffenders: []PageSummary{},
+		Pages:          pages,
+		Duration:       duration,
+	}
+
+	for _, page := range pages {
+		if page.Result == nil {
+			report.Failed++
+			continue
+		}
+		report.Succeeded++
+		report.TotalLinks += len(page.Result.Links)
+		report.BrokenLinks += page.BrokenLinks
+		if page.Result.HasLoginForm {
+			report.LoginFormPages = append(report.LoginFormPages, page.URL)
+		}
+		version := page.Result.HTMLVersion
+		if version == "" {
+			version = "Unknown"
+		}
+		report.HTMLVersions[version]++
+		if page.BrokenLinks > 0 {
+			report.WorstOffenders = append(report.WorstOffenders, PageSummary{
+				URL:         page.URL,
+				Title:       page.Result.Title,
+				TotalLinks:  len(page.Result.Links),
+				BrokenLinks: page.BrokenLinks,
+			})
+		}
+	}
+
+	sort.SliceStable(report.WorstOffenders, func(i, j int) bool {
+		return report.WorstOffenders[i].BrokenLinks > report.WorstOffenders[j].BrokenLinks
+	})
+	if len(report.WorstOffenders) > worstOffendersLimit {
+		report.WorstOffenders = report.WorstOffenders[:worstOffendersLimit]
+	}
+
+	return report
+}
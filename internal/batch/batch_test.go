@@ -0,0 +1,99 @@
+package batch
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"home24/internal/analyzer"
+)
+
+// fakeAnalyzer returns canned results and tracks how many analyses run at once
+type fakeAnalyzer struct {
+	results  map[string]*analyzer.AnalysisResult
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (f *fakeAnalyzer) Analyze(ctx context.Context, urlStr string) (*analyzer.AnalysisResult, error) {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		seen := f.maxSeen.Load()
+		if n <= seen || f.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	result, ok := f.results[urlStr]
+	if !ok {
+		return nil, analyzer.NewAnalysisError(analyzer.ErrFetchFailed, "failed to fetch page", nil)
+	}
+	return result, nil
+}
+
+// links builds n links of which the first broken ones are inaccessible
+func links(n, broken int) []analyzer.LinkInfo {
+	var result []analyzer.LinkInfo
+	for i := 0; i < n; i++ {
+		result = append(result, analyzer.LinkInfo{URL: "/link", Accessible: i >= broken})
+	}
+	return result
+}
+
+// Test URL lists are parsed, deduplicated and validated
+func TestParseURLList(t *testing.T) {
+	urls, err := ParseURLList(strings.NewReader(`
+		# landing pages
+		https://example.com/a
+		https://example.com/b
+
+		https://example.com/a
+	`))
+	if err != nil {
+		t.Fatalf("Error parsing list: %v", err)
+	}
+	if len(urls) != 2 || urls[0] != "https://example.com/a" || urls[1] != "https://example.com/b" {
+		t.Errorf("Expected 2 unique URLs, got %v", urls)
+	}
+
+	_, err = ParseURLList(strings.NewReader("https://example.com\nftp://example.com\nnot a url"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected errors for lines 2 and 3, got %v", err)
+	}
+}
+
+// Test the report aggregates the page results
+func TestRunAggregates(t *testing.T) {
+	fake := &fakeAnalyzer{results: map[string]*analyzer.AnalysisResult{
+		"https://example.com/a": {Title: "A", HTMLVersion: "HTML 5", Links: links(4, 3), HasLoginForm: true},
+		"https://example.com/b": {Title: "B", HTMLVersion: "HTML 5", Links: links(2, 0)},
+		"https://example.com/c": {Title: "C", HTMLVersion: "HTML 4.01", Links: links(5, 1)},
+	}}
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c", "https://example.com/missing"}
+
+	report := Run(context.Background(), fake, urls, 2)
+
+	if report.Total != 4 || report.Succeeded != 3 || report.Failed != 1 {
+		t.Errorf("Expected 4 total, 3 succeeded, 1 failed, got %d, %d, %d", report.Total, report.Succeeded, report.Failed)
+	}
+	if report.TotalLinks != 11 || report.BrokenLinks != 4 {
+		t.Errorf("Expected 11 links of which 4 broken, got %d and %d", report.TotalLinks, report.BrokenLinks)
+	}
+	if len(report.WorstOffenders) != 2 || report.WorstOffenders[0].URL != "https://example.com/a" {
+		t.Errorf("Expected page a to be the worst offender, got %+v", report.WorstOffenders)
+	}
+	if len(report.LoginFormPages) != 1 || report.LoginFormPages[0] != "https://example.com/a" {
+		t.Errorf("Expected page a to have a login form, got %v", report.LoginFormPages)
+	}
+	if report.HTMLVersions["HTML 5"] != 2 || report.HTMLVersions["HTML 4.01"] != 1 {
+		t.Errorf("Unexpected HTML version distribution %v", report.HTMLVersions)
+	}
+	if report.Pages[3].Error == nil || report.Pages[3].Error.Code != analyzer.ErrFetchFailed {
+		t.Errorf("Expected the missing page to fail with %s, got %+v", analyzer.ErrFetchFailed, report.Pages[3].Error)
+	}
+	if fake.maxSeen.Load() > 2 {
+		t.Errorf("Expected at most 2 analyses in flight, saw %d", fake.maxSeen.Load())
+	}
+}
@@ -0,0 +1,164 @@
+package compare
+
+import (
+	"sort"
+
+	"home24/internal/analyzer"
+)
+
+// StringChange is a text value that differs between two analyses
+type StringChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// BoolChange is a flag that differs between two analyses
+type BoolChange struct {
+	Before bool `json:"before"`
+	After  bool `json:"after"`
+}
+
+// HeadingDelta is the change in the number of headings of one level
+type HeadingDelta struct {
+	Level  string `json:"level"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	Delta  int    `json:"delta"`
+}
+
+// Diff lists what changed between two analyses of a page. Fields of values
+// that didn't change are nil or empty. LinksSkipped is set when either
+// analysis was stopped early; its links are incomplete, so the link lists
+// are left empty.
+type Diff struct {
+	Title        *StringChange       `json:"title,omitempty"`
+	HTMLVersion  *StringChange       `json:"html_version,omitempty"`
+	LoginForm    *BoolChange         `json:"login_form,omitempty"`
+	Headings     []HeadingDelta      `json:"headings"`
+	AddedLinks   []analyzer.LinkInfo `json:"added_links"`
+	RemovedLinks []analyzer.LinkInfo `json:"removed_links"`
+	NewlyBroken  []analyzer.LinkInfo `json:"newly_broken_links"`
+	Fixed        []analyzer.LinkInfo `json:"fixed_links"`
+	LinksSkipped bool                `json:"links_skipped,omitempty"`
+}
+
+// Changed reports whether anything differs
+func (d *Diff) Changed() bool {
+	return d.Title != nil || d.HTMLVersion != nil || d.LoginForm != nil ||
+		len(d.Headings) > 0 || len(d.AddedLinks) > 0 || len(d.RemovedLinks) > 0 ||
+		len(d.NewlyBroken) > 0 || len(d.Fixed) > 0
+}
+
+// Regressed reports whether links broke since the earlier analysis
+func (d *Diff) Regressed() bool {
+	return len(d.NewlyBroken) > 0
+}
+
+// Compare lists the changes from the before to the after analysis. Links are
+// matched by their URL as it appears in the page. A link is newly broken if
+// it is broken now and was either missing or accessible before; links that
+// appeared broken are therefore reported as both added and newly broken.
+// The links of partial analyses are not compared.
+func Compare(before, after *analyzer.AnalysisResult) *Diff {
+	diff := &Diff{
+		Headings:     []HeadingDelta{},
+		AddedLinks:   []analyzer.LinkInfo{},
+		RemovedLinks: []analyzer.LinkInfo{},
+		NewlyBroken:  []analyzer.LinkInfo{},
+		Fixed:        []analyzer.LinkInfo{},
+	}
+
+	if before.Title != after.Title {
+		diff.Title = &StringChange{Before: before.Title, After: after.Title}
+	}
+	if before.HTMLVersion != after.HTMLVersion {
+		diff.HTMLVersion = &StringChange{Before: before.HTMLVersion, After: after.HTMLVersion}
+	}
+	if before.HasLoginForm != after.HasLoginForm {
+		diff.LoginForm = &BoolChange{Before: before.HasLoginForm, After: after.HasLoginForm}
+	}
+
+	diff.Headings = compareHeadings(before.Headings, after.Headings)
+
+	if before.Partial || after.Partial {
+		diff.LinksSkipped = true
+		return diff
+	}
+
+	beforeLinks := linksByURL(before.Links)
+	afterLinks := linksByURL(after.Links)
+	for _, link := range uniqueLinks(after.Links) {
+		old, existed := beforeLinks[link.URL]
+		if !existed {
+			diff.AddedLinks = append(diff.AddedLinks, link)
+		}
+		if !link.Accessible && (!existed || old.Accessible) {
+			diff.NewlyBroken = append(diff.NewlyBroken, link)
+		}
+		if link.Accessible && existed && !old.Accessible {
+			diff.Fixed = append(diff.Fixed, link)
+		}
+	}
+	for _, link := range uniqueLinks(before.Links) {
+		if _, exists := afterLinks[link.URL]; !exists {
+			diff.RemovedLinks = append(diff.RemovedLinks, link)
+		}
+	}
+
+	return diff
+}
+
+// compareHeadings returns the levels whose heading count changed, in order
+func compareHeadings(before, after map[string]int) []HeadingDelta {
+	levels := make(map[string]bool)
+	for level := range before {
+		levels[level] = true
+	}
+	for level := range after {
+		levels[level] = true
+	}
+
+	deltas := []HeadingDelta{}
+	for level := range levels {
+		if before[level] != after[level] {
+			deltas = append(deltas, HeadingDelta{
+				Level:  level,
+				Before: before[level],
+				After:  after[level],
+				Delta:  after[level] - before[level],
+			})
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].Level < deltas[j].Level
+	})
+	return deltas
+}
+
+// linksByURL indexes links by their URL. A page can link to the same URL
+// more than once; a link counts as accessible if any of its checks was.
+func linksByURL(links []analyzer.LinkInfo) map[string]analyzer.LinkInfo {
+	index := make(map[string]analyzer.LinkInfo, len(links))
+	for _, link := range links {
+		if existing, ok := index[link.URL]; ok && existing.Accessible {
+			continue
+		}
+		index[link.URL] = link
+	}
+	return index
+}
+
+// uniqueLinks returns the links in page order without repeated URLs, using
+// the same entry as linksByURL for each URL
+func uniqueLinks(links []analyzer.LinkInfo) []analyzer.LinkInfo {
+	index := linksByURL(links)
+	seen := make(map[string]bool, len(index))
+	unique := make([]analyzer.LinkInfo, 0, len(index))
+	for _, link := range links {
+		if !seen[link.URL] {
+			seen[link.URL] = true
+			unique = append(unique, index[link.URL])
+		}
+	}
+	return unique
+}
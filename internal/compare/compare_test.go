@@ -0,0 +1,138 @@
+package compare
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"home24/internal/analyzer"
+)
+
+// urls returns the URLs of the links
+func urls(links []analyzer.LinkInfo) []string {
+	result := []string{}
+	for _, link := range links {
+		result = append(result, link.URL)
+	}
+	return result
+}
+
+// Test every kind of change is reported
+func TestCompare(t *testing.T) {
+	before := &analyzer.AnalysisResult{
+		Title:        "Home",
+		HTMLVersion:  "HTML 4.01",
+		HasLoginForm: false,
+		Headings:     map[string]int{"h1": 1, "h2": 4, "h3": 2},
+		Links: []analyzer.LinkInfo{
+			{URL: "/kept", Accessible: true},
+			{URL: "/breaks", Accessible: true},
+			{URL: "/fixed", Accessible: false},
+			{URL: "/removed", Accessible: true},
+			{URL: "/still-broken", Accessible: false},
+		},
+	}
+	after := &analyzer.AnalysisResult{
+		Title:        "Home - New",
+		HTMLVersion:  "HTML 5",
+		HasLoginForm: true,
+		Headings:     map[string]int{"h1": 1, "h2": 3, "h4": 1},
+		Links: []analyzer.LinkInfo{
+			{URL: "/kept", Accessible: true},
+			{URL: "/breaks", Accessible: false},
+			{URL: "/fixed", Accessible: true},
+			{URL: "/still-broken", Accessible: false},
+			{URL: "/added", Accessible: true},
+			{URL: "/added-broken", Accessible: false},
+			{URL: "/added", Accessible: true},
+		},
+	}
+
+	diff := Compare(before, after)
+
+	if diff.Title == nil || diff.Title.Before != "Home" || diff.Title.After != "Home - New" {
+		t.Errorf("Unexpected title change %+v", diff.Title)
+	}
+	if diff.HTMLVersion == nil || diff.HTMLVersion.After != "HTML 5" {
+		t.Errorf("Unexpected HTML version change %+v", diff.HTMLVersion)
+	}
+	if diff.LoginForm == nil || diff.LoginForm.Before || !diff.LoginForm.After {
+		t.Errorf("Unexpected login form change %+v", diff.LoginForm)
+	}
+
+	expectedHeadings := []HeadingDelta{
+		{Level: "h2", Before: 4, After: 3, Delta: -1},
+		{Level: "h3", Before: 2, After: 0, Delta: -2},
+		{Level: "h4", Before: 0, After: 1, Delta: 1},
+	}
+	if !reflect.DeepEqual(diff.Headings, expectedHeadings) {
+		t.Errorf("Expected heading deltas %+v, got %+v", expectedHeadings, diff.Headings)
+	}
+
+	tests := []struct {
+		name     string
+		links    []analyzer.LinkInfo
+		expected []string
+	}{
+		{"added", diff.AddedLinks, []string{"/added", "/added-broken"}},
+		{"removed", diff.RemovedLinks, []string{"/removed"}},
+		{"newly broken", diff.NewlyBroken, []string{"/breaks", "/added-broken"}},
+		{"fixed", diff.Fixed, []string{"/fixed"}},
+	}
+	for _, tt := range tests {
+		if got := urls(tt.links); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Expected %s links %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	if !diff.Changed() || !diff.Regressed() {
+		t.Error("Expected the diff to report changes and a regression")
+	}
+}
+
+// Test identical analyses have an empty diff that encodes without nulls
+func TestCompareUnchanged(t *testing.T) {
+	result := &analyzer.AnalysisResult{
+		Title:    "Home",
+		Headings: map[string]int{"h1": 1},
+		Links:    []analyzer.LinkInfo{{URL: "/a", Accessible: true}, {URL: "/b"}},
+	}
+
+	diff := Compare(result, result)
+	if diff.Changed() || diff.Regressed() {
+		t.Errorf("Expected no changes, got %+v", diff)
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("Error encoding diff: %v", err)
+	}
+	expected := `{"headings":[],"added_links":[],"removed_links":[],"newly_broken_links":[],"fixed_links":[]}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+// Test the links of partial analyses are not compared
+func TestComparePartial(t *testing.T) {
+	before := &analyzer.AnalysisResult{
+		Title: "Home",
+		Links: []analyzer.LinkInfo{{URL: "/a", Accessible: true}, {URL: "/b", Accessible: true}},
+	}
+	after := &analyzer.AnalysisResult{
+		Title:   "Home - New",
+		Links:   []analyzer.LinkInfo{{URL: "/a", Accessible: false}},
+		Partial: true,
+	}
+
+	diff := Compare(before, after)
+	if !diff.LinksSkipped {
+		t.Error("Expected the links to be skipped")
+	}
+	if len(diff.RemovedLinks) > 0 || len(diff.NewlyBroken) > 0 || diff.Regressed() {
+		t.Errorf("Expected no link changes, got %+v", diff)
+	}
+	if diff.Title == nil {
+		t.Error("Expected the title change to be reported")
+	}
+}
@@ -1,58 +1,116 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/batch"
+	"home24/internal/jobs"
+	"home24/internal/ratelimit"
+	"home24/internal/sitemap"
+	"home24/internal/store"
+	"home24/internal/tracing"
+	"home24/internal/webhook"
+	"home24/pkg/logger"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig            `yaml:"server"`
-	Analyzer analyzer.AnalyzerConfig `yaml:"analyzer"`
+	Server    ServerConfig            `yaml:"server"`
+	Analyzer  analyzer.AnalyzerConfig `yaml:"analyzer"`
+	Jobs      jobs.Config             `yaml:"jobs"`
+	Batch     batch.Config            `yaml:"batch"`
+	Sitemap   sitemap.Config          `yaml:"sitemap"`
+	Webhooks  webhook.Config          `yaml:"webhooks"`
+	History   store.Config            `yaml:"history"`
+	Tracing   tracing.Config          `yaml:"tracing"`
+	Logging   logger.Config           `yaml:"logging"`
+	RateLimit ratelimit.Config        `yaml:"rateLimit"`
+	Auth      auth.Config             `yaml:"auth"`
 }
 
 // ServerConfig holds server-specific configuration
 type ServerConfig struct {
-	Port         string `yaml:"port"`
-	ReadTimeout  string `yaml:"readTimeout"`
-	WriteTimeout string `yaml:"writeTimeout"`
-	IdleTimeout  string `yaml:"idleTimeout"`
+	Port         string        `yaml:"port"`
+	GRPCPort     string        `yaml:"grpcPort"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	IdleTimeout  time.Duration `yaml:"idleTimeout"`
+
+	// How often the config file is checked for changes; 0 disables it
+	ConfigReloadInterval time.Duration `yaml:"configReloadInterval"`
+
+	// How long running requests, analyses and jobs may take to finish on
+	// shutdown before they are canceled
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	// AdminAddress is the host and port of the listener serving the
+	// profiler and debug endpoints, e.g. "localhost:6060". Empty disables
+	// it.
+	AdminAddress string `yaml:"adminAddress"`
 }
 
-// LoadConfig loads configuration from a YAML file
-func LoadConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading config file: %w", err)
+// Default returns the configuration used for settings that are not set
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:                 "8080",
+			GRPCPort:             "9090",
+			ReadTimeout:          10 * time.Second,
+			WriteTimeout:         30 * time.Second,
+			IdleTimeout:          120 * time.Second,
+			ConfigReloadInterval: 5 * time.Second,
+			ShutdownTimeout:      30 * time.Second,
+		},
+		Analyzer:  analyzer.DefaultConfig(),
+		Jobs:      jobs.DefaultConfig(),
+		Batch:     batch.DefaultConfig(),
+		Sitemap:   sitemap.DefaultConfig(),
+		Webhooks:  webhook.DefaultConfig(),
+		History:   store.DefaultConfig(),
+		Tracing:   tracing.DefaultConfig(),
+		Logging:   logger.DefaultConfig(),
+		RateLimit: ratelimit.DefaultConfig(),
+		Auth:      auth.DefaultConfig(),
 	}
+}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("error parsing config file: %w", err)
-	}
+// LoadConfig loads configuration from a YAML file on top of the defaults,
+// without environment or command-line overrides
+func LoadConfig(configPath string) (*Config, error) {
+	loader := &Loader{Path: configPath, lookupEnv: noEnv}
+	return loader.Load()
+}
 
-	// Set defaults if not specified
-	if config.Server.Port == "" {
-		config.Server.Port = "8080"
-	}
-	if config.Server.ReadTimeout == "" {
-		config.Server.ReadTimeout = "10s"
+// decodeFile decodes the content of a config file onto config. Unknown keys
+// are rejected so typos don't go unnoticed.
+func decodeFile(data []byte, config *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(config)
+	if errors.Is(err, io.EOF) {
+		// An empty file sets nothing
+		return nil
 	}
-	if config.Server.WriteTimeout == "" {
-		config.Server.WriteTimeout = "30s"
-	}
-	if config.Server.IdleTimeout == "" {
-		config.Server.IdleTimeout = "120s"
+	if err != nil {
+		return fmt.Errorf("error parsing config file: %w", err)
 	}
+	return nil
+}
 
-	// If analyzer config is empty, use defaults
-	if config.Analyzer.Timeout == 0 {
-		config.Analyzer = analyzer.DefaultConfig()
+// readFile reads a config file
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
-
-	return &config, nil
+	return data, nil
 }
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file setting the analyzer user agent
+func writeConfig(t *testing.T, path, userAgent string) {
+	data := "analyzer:\n  timeout: \"5s\"\n  userAgent: \"" + userAgent + "\"\n  retryAttempts: 2\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+}
+
+// Test the camelCase analyzer keys bind and server defaults are filled in
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.yaml")
+	writeConfig(t, path, "Test/1.0")
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	if config.Analyzer.Timeout != 5*time.Second || config.Analyzer.UserAgent != "Test/1.0" || config.Analyzer.RetryAttempts != 2 {
+		t.Errorf("Unexpected analyzer config %+v", config.Analyzer)
+	}
+	if config.Server.Port != "8080" || config.Server.ConfigReloadInterval != 5*time.Second {
+		t.Errorf("Unexpected server config %+v", config.Server)
+	}
+}
+
+// Test unknown keys are rejected and every invalid setting is reported
+func TestStrictValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.yaml")
+
+	os.WriteFile(path, []byte("analyzer:\n  maxConcurentLinks: 5\n"), 0o644)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "field maxConcurentLinks not found") {
+		t.Errorf("Expected the misspelled key to be rejected, got %v", err)
+	}
+
+	os.WriteFile(path, []byte(`
+server:
+  port: "http"
+  readTimeout: "0s"
+  adminAddress: "6060"
+analyzer:
+  userAgent: ""
+  retryAttempts: 0
+  admission:
+    queueTimeout: "0s"
+history:
+  driver: "postgres"
+`), 0o644)
+	_, err := LoadConfig(path)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	expected := []string{"server.port", "server.readTimeout", "server.adminAddress", "analyzer.userAgent", "analyzer.retryAttempts", "analyzer.admission", "history.driver"}
+	if len(validationErr.Problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %q", len(expected), validationErr.Problems)
+	}
+	for i, path := range expected {
+		if !strings.HasPrefix(validationErr.Problems[i], path+": ") {
+			t.Errorf("Expected a problem with %s, got %q", path, validationErr.Problems[i])
+		}
+	}
+
+	os.WriteFile(path, []byte("server:\n  readTimeout: \"soon\"\n"), 0o644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Expected an invalid duration to be rejected")
+	}
+}
+
+// Test flags override environment variables, which override the file
+func TestOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.yaml")
+	writeConfig(t, path, "File/1.0")
+	t.Setenv("CONFIG_PATH", path)
+	t.Setenv("ANALYZER_USER_AGENT", "Env/1.0")
+	t.Setenv("ANALYZER_RETRY_ATTEMPTS", "4")
+	t.Setenv("ANALYZER_SERVER_PORT", "8181")
+	t.Setenv("ANALYZER_NETWORK_INSECURE_SKIP_VERIFY_HOSTS", "a.example.com, b.example.com")
+
+	loader, err := NewLoader([]string{"-analyzer.user-agent=Flag/1.0", "-analyzer.timeout", "45s"})
+	if err != nil {
+		t.Fatalf("Error parsing flags: %v", err)
+	}
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+
+	if loader.Path != path {
+		t.Errorf("Expected the config path from CONFIG_PATH, got %q", loader.Path)
+	}
+	a := config.Analyzer
+	if a.UserAgent != "Flag/1.0" || a.Timeout != 45*time.Second || a.RetryAttempts != 4 {
+		t.Errorf("Unexpected analyzer config %+v", a)
+	}
+	if len(a.Network.InsecureSkipVerifyHosts) != 2 || a.Network.InsecureSkipVerifyHosts[1] != "b.example.com" {
+		t.Errorf("Unexpected hosts %q", a.Network.InsecureSkipVerifyHosts)
+	}
+	if config.Server.Port != "8181" || config.Server.GRPCPort != "9090" {
+		t.Errorf("Unexpected server config %+v", config.Server)
+	}
+
+	t.Setenv("ANALYZER_MAX_DEPTH", "deep")
+	if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "ANALYZER_MAX_DEPTH") {
+		t.Errorf("Expected the invalid environment variable to be reported, got %v", err)
+	}
+}
+
+// Test the names settings are overridden with
+func TestSettingNames(t *testing.T) {
+	names := make(map[string]setting)
+	for _, s := range allSettings() {
+		names[s.path] = s
+	}
+
+	tests := []struct {
+		path, env, flag string
+	}{
+		{"analyzer.maxConcurrentLinks", "ANALYZER_MAX_CONCURRENT_LINKS", "analyzer.max-concurrent-links"},
+		{"analyzer.network.proxyURL", "ANALYZER_NETWORK_PROXY_URL", "analyzer.network.proxy-url"},
+		{"analyzer.network.allowedNetworks", "ANALYZER_NETWORK_ALLOWED_NETWORKS", "analyzer.network.allowed-networks"},
+		{"analyzer.admission.queueTimeout", "ANALYZER_ADMISSION_QUEUE_TIMEOUT", "analyzer.admission.queue-timeout"},
+		{"server.grpcPort", "ANALYZER_SERVER_GRPC_PORT", "server.grpc-port"},
+		{"server.shutdownTimeout", "ANALYZER_SERVER_SHUTDOWN_TIMEOUT", "server.shutdown-timeout"},
+		{"server.adminAddress", "ANALYZER_SERVER_ADMIN_ADDRESS", "server.admin-address"},
+		{"jobs.checkpointFile", "ANALYZER_JOBS_CHECKPOINT_FILE", "jobs.checkpoint-file"},
+		{"batch.maxURLs", "ANALYZER_BATCH_MAX_URLS", "batch.max-urls"},
+		{"sitemap.maxIndexDepth", "ANALYZER_SITEMAP_MAX_INDEX_DEPTH", "sitemap.max-index-depth"},
+		{"webhooks.deadLetterFile", "ANALYZER_WEBHOOKS_DEAD_LETTER_FILE", "webhooks.dead-letter-file"},
+		{"history.path", "ANALYZER_HISTORY_PATH", "history.path"},
+		{"logging.addSource", "ANALYZER_LOGGING_ADD_SOURCE", "logging.add-source"},
+		{"logging.sampling.initial", "ANALYZER_LOGGING_SAMPLING_INITIAL", "logging.sampling.initial"},
+		{"rateLimit.requestsPerMinute", "ANALYZER_RATE_LIMIT_REQUESTS_PER_MINUTE", "rate-limit.requests-per-minute"},
+		{"auth.enabled", "ANALYZER_AUTH_ENABLED", "auth.enabled"},
+	}
+	for _, tt := range tests {
+		s, ok := names[tt.path]
+		if !ok {
+			t.Errorf("Missing setting %s", tt.path)
+			continue
+		}
+		if s.env != tt.env || s.flag != tt.flag {
+			t.Errorf("Expected %s and -%s for %s, got %s and -%s", tt.env, tt.flag, tt.path, s.env, s.flag)
+		}
+	}
+}
+
+// Test changes to the file are applied and rejected ones keep the current
+// configuration
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.yaml")
+	writeConfig(t, path, "Start/1.0")
+
+	applied := make(chan string, 10)
+	watcher := NewWatcher(&Loader{Path: path, lookupEnv: noEnv}, 10*time.Millisecond, func(config *Config) error {
+		if config.Analyzer.UserAgent == "Rejected/1.0" {
+			return errors.New("rejected")
+		}
+		applied <- config.Analyzer.UserAgent
+		return nil
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	// An unchanged file isn't applied again
+	select {
+	case ua := <-applied:
+		t.Fatalf("Unexpected reload of the unchanged file with %q", ua)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeConfig(t, path, "Edited/1.0")
+	select {
+	case ua := <-applied:
+		if ua != "Edited/1.0" {
+			t.Errorf("Expected the edited config, got %q", ua)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The edited config was not applied")
+	}
+
+	writeConfig(t, path, "Rejected/1.0")
+	if err := watcher.Reload(); err == nil {
+		t.Error("Expected the rejected config to fail the reload")
+	}
+	if err := os.WriteFile(path, []byte("analyzer: [broken"), 0o644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if err := watcher.Reload(); err == nil {
+		t.Error("Expected invalid YAML to fail the reload")
+	}
+
+	// An empty file, e.g. one truncated before it is rewritten, is never
+	// applied
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if err := watcher.Reload(); !errors.Is(err, errEmptyFile) {
+		t.Errorf("Expected the empty file to fail the reload, got %v", err)
+	}
+	select {
+	case ua := <-applied:
+		t.Fatalf("Unexpected reload of the empty file with %q", ua)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// An explicit trigger reloads even an unchanged file
+	writeConfig(t, path, "Triggered/1.0")
+	watcher.Reload()
+	watcher.Trigger()
+	for _, expected := range []string{"Triggered/1.0", "Triggered/1.0"} {
+		select {
+		case ua := <-applied:
+			if ua != expected {
+				t.Errorf("Expected %q, got %q", expected, ua)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("The triggered reload was not applied")
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The prefix of the environment variables that override settings
+const envPrefix = "ANALYZER_"
+
+// The config file used when neither -config nor CONFIG_PATH is set
+const defaultPath = "config/application.yaml"
+
+// Loader builds the configuration from these sources, each overriding the
+// ones before it:
+//
+//  1. the defaults
+//  2. the config file
+//  3. ANALYZER_* environment variables
+//  4. command-line flags
+//
+// The result is validated as a whole. A Loader can load again, e.g. when the
+// file changes, and applies the same overrides every time.
+type Loader struct {
+	Path string
+
+	lookupEnv func(string) (string, bool)
+	flags     map[string]string
+}
+
+// NewLoader parses the command-line flags. The config file is taken from
+// -config, then CONFIG_PATH, then config/application.yaml.
+func NewLoader(args []string) (*Loader, error) {
+	l := &Loader{
+		lookupEnv: os.LookupEnv,
+		flags:     make(map[string]string),
+	}
+
+	flags := flag.NewFlagSet("analyzer", flag.ContinueOnError)
+	flags.StringVar(&l.Path, "config", "", "path of the config file (env CONFIG_PATH, default "+defaultPath+")")
+	for _, s := range allSettings() {
+		flags.Var(&flagValue{setting: s.path, values: l.flags}, s.flag, "sets "+s.path+" (env "+s.env+")")
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of analyzer:\n\nSettings are read from the defaults, the config file, ANALYZER_* environment\nvariables and these flags, each overriding the ones before.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	if l.Path == "" {
+		l.Path = os.Getenv("CONFIG_PATH")
+	}
+	if l.Path == "" {
+		l.Path = defaultPath
+	}
+	return l, nil
+}
+
+// Load reads the config file and applies the overrides
+func (l *Loader) Load() (*Config, error) {
+	data, err := readFile(l.Path)
+	if err != nil {
+		return nil, err
+	}
+	return l.parse(data)
+}
+
+// parse builds the configuration from the content of the config file
+func (l *Loader) parse(data []byte) (*Config, error) {
+	config := Default()
+	if err := decodeFile(data, &config); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	root := reflect.ValueOf(&config).Elem()
+	for _, s := range allSettings() {
+		if raw, ok := l.lookupEnv(s.env); ok {
+			if err := setValue(root.FieldByIndex(s.index), raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid value %q: %v", s.env, raw, err))
+			}
+		}
+	}
+	for _, s := range allSettings() {
+		if raw, ok := l.flags[s.path]; ok {
+			if err := setValue(root.FieldByIndex(s.index), raw); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s: invalid value %q: %v", s.flag, raw, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// noEnv is a lookupEnv that finds nothing
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+// setting is a configurable value and the names it is overridden with
+type setting struct {
+	path  string // the key in the config file, e.g. analyzer.network.proxyURL
+	env   string // e.g. ANALYZER_NETWORK_PROXY_URL
+	flag  string // e.g. analyzer.network.proxy-url
+	index []int  // the field in Config
+}
+
+// allSettings lists every setting of Config. Sections are walked down to
+// their values; lists and maps are single settings.
+func allSettings() []setting {
+	var settings []setting
+	var walk func(t reflect.Type, keys []string, index []int)
+	walk = func(t reflect.Type, keys []string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if key == "" || key == "-" {
+				continue
+			}
+			fieldKeys := append(append([]string{}, keys...), key)
+			fieldIndex := append(append([]int{}, index...), i)
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, fieldKeys, fieldIndex)
+				continue
+			}
+			settings = append(settings, newSetting(fieldKeys, fieldIndex))
+		}
+	}
+	walk(reflect.TypeOf(Config{}), nil, nil)
+
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].path < settings[j].path
+	})
+	return settings
+}
+
+// newSetting derives the names of a setting from its keys. The analyzer
+// section is implied in environment variables, so analyzer.timeout is
+// ANALYZER_TIMEOUT while server.port is ANALYZER_SERVER_PORT.
+func newSetting(keys []string, index []int) setting {
+	envKeys := keys
+	if keys[0] == "analyzer" {
+		envKeys = keys[1:]
+	}
+	var env, flag []string
+	for _, key := range envKeys {
+		env = append(env, strings.ToUpper(splitWords(key, "_")))
+	}
+	for _, key := range keys {
+		flag = append(flag, strings.ToLower(splitWords(key, "-")))
+	}
+	return setting{
+		path:  strings.Join(keys, "."),
+		env:   envPrefix + strings.Join(env, "_"),
+		flag:  strings.Join(flag, "."),
+		index: index,
+	}
+}
+
+// splitWords separates the words of a camelCase key, keeping acronyms
+// together, also in the plural: proxyURL becomes proxy<sep>URL and maxURLs
+// max<sep>URLs
+func splitWords(key, sep string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			plural := nextLower && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower && !plural) {
+				b.WriteString(sep)
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// setValue sets a setting from its text form. Strings are taken as they
+// are, lists of strings may be comma separated and everything else uses
+// the YAML syntax of the config file, e.g. 30s, true or {"a": "b"}.
+func setValue(field reflect.Value, raw string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		values := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = reflect.Append(values, reflect.ValueOf(item).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(values)
+		return nil
+	}
+
+	value := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(raw), value.Interface()); err != nil {
+		return err
+	}
+	field.Set(value.Elem())
+	return nil
+}
+
+// flagValue records the raw value of a setting flag; it is applied and
+// checked when the configuration is loaded
+type flagValue struct {
+	setting string
+	values  map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return f.values[f.setting]
+}
+
+func (f *flagValue) Set(value string) error {
+	f.values[f.setting] = value
+	return nil
+}
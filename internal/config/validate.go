@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/store"
+	"home24/internal/tracing"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Valid Prometheus metric name prefixes
+var metricPrefixPattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Validate checks every setting and reports all problems at once
+func (c *Config) Validate() error {
+	var v validator
+
+	v.port("server.port", c.Server.Port)
+	v.port("server.grpcPort", c.Server.GRPCPort)
+	if c.Server.Port != "" && c.Server.Port == c.Server.GRPCPort {
+		v.add("server.grpcPort", "must differ from server.port")
+	}
+	v.duration("server.readTimeout", c.Server.ReadTimeout, time.Second, time.Hour)
+	v.duration("server.writeTimeout", c.Server.WriteTimeout, time.Second, time.Hour)
+	v.duration("server.idleTimeout", c.Server.IdleTimeout, time.Second, time.Hour)
+	if c.Server.ConfigReloadInterval != 0 {
+		v.duration("server.configReloadInterval", c.Server.ConfigReloadInterval, time.Second, time.Hour)
+	}
+	v.duration("server.shutdownTimeout", c.Server.ShutdownTimeout, time.Second, time.Hour)
+	if c.Server.AdminAddress != "" {
+		if _, port, err := net.SplitHostPort(c.Server.AdminAddress); err != nil {
+			v.add("server.adminAddress", fmt.Sprintf("must be host:port, got %q", c.Server.AdminAddress))
+		} else {
+			v.port("server.adminAddress", port)
+		}
+	}
+
+	a := c.Analyzer
+	v.duration("analyzer.timeout", a.Timeout, time.Second, 10*time.Minute)
+	v.number("analyzer.maxConcurrentLinks", a.MaxConcurrentLinks, 1, 1000)
+	v.required("analyzer.userAgent", a.UserAgent)
+	v.number("analyzer.retryAttempts", a.RetryAttempts, 1, 10)
+	v.number("analyzer.maxLinksPerPage", a.MaxLinksPerPage, 1, 100000)
+	v.number("analyzer.maxDepth", a.MaxDepth, 0, 10)
+	if a.MetricsPrefix != "" && !metricPrefixPattern.MatchString(a.MetricsPrefix) {
+		v.add("analyzer.metricsPrefix", "must be a valid Prometheus metric name")
+	}
+	v.nested("analyzer.network", a.Network.Validate())
+	v.nested("analyzer.admission", a.Admission.Validate())
+
+	v.number("jobs.workers", c.Jobs.Workers, 1, 100)
+	v.number("jobs.queueSize", c.Jobs.QueueSize, 1, 100000)
+	v.duration("jobs.retention", c.Jobs.Retention, time.Minute, 7*24*time.Hour)
+
+	v.number("batch.concurrency", c.Batch.Concurrency, 1, 100)
+	v.number("batch.maxURLs", c.Batch.MaxURLs, 1, 100000)
+	v.number("sitemap.maxURLs", c.Sitemap.MaxURLs, 1, 100000)
+	v.number("sitemap.maxIndexDepth", c.Sitemap.MaxIndexDepth, 0, 10)
+
+	w := c.Webhooks
+	v.number("webhooks.maxAttempts", w.MaxAttempts, 1, 20)
+	v.duration("webhooks.initialBackoff", w.InitialBackoff, time.Millisecond, time.Hour)
+	v.duration("webhooks.maxBackoff", w.MaxBackoff, time.Millisecond, 24*time.Hour)
+	if w.MaxBackoff < w.InitialBackoff {
+		v.add("webhooks.maxBackoff", "must not be shorter than webhooks.initialBackoff")
+	}
+	v.duration("webhooks.timeout", w.Timeout, time.Second, 5*time.Minute)
+	v.nested("webhooks.endpoints", w.Validate())
+	if _, err := analyzer.NewGuard(w.AllowedNetworks); err != nil {
+		v.add("webhooks.allowedNetworks", err.Error())
+	}
+
+	switch c.History.Driver {
+	case store.DriverSQLite:
+		v.required("history.path", c.History.Path)
+	case store.DriverMemory:
+	default:
+		v.add("history.driver", fmt.Sprintf("must be %q or %q, got %q", store.DriverSQLite, store.DriverMemory, c.History.Driver))
+	}
+
+	t := c.Tracing
+	if t.Enabled {
+		v.required("tracing.serviceName", t.ServiceName)
+		if t.Exporter != tracing.ExporterOTLPGRPC && t.Exporter != tracing.ExporterOTLPHTTP {
+			v.add("tracing.exporter", fmt.Sprintf("must be %q or %q, got %q", tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP, t.Exporter))
+		}
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		v.add("tracing.sampleRatio", fmt.Sprintf("must be between 0 and 1, got %v", t.SampleRatio))
+	}
+
+	v.nested("logging", c.Logging.Validate())
+	if c.RateLimit.Enabled {
+		v.nested("rateLimit", c.RateLimit.Validate())
+	}
+	if c.Auth.Enabled {
+		v.nested("auth.keys", c.Auth.Validate())
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects validation problems
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(path, problem string) {
+	v.problems = append(v.problems, path+": "+problem)
+}
+
+func (v *validator) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(path, "is required")
+	}
+}
+
+func (v *validator) number(path string, value, min, max int) {
+	if value < min || value > max {
+		v.add(path, fmt.Sprintf("must be between %d and %d, got %d", min, max, value))
+	}
+}
+
+func (v *validator) duration(path string, value, min, max time.Duration) {
+	if value < min || value > max {
+		v.add(path, fmt.Sprintf("must be between %s and %s, got %s", min, max, value))
+	}
+}
+
+func (v *validator) port(path, value string) {
+	if value == "" {
+		v.add(path, "is required")
+		return
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		v.add(path, fmt.Sprintf("must be a port between 1 and 65535, got %q", value))
+	}
+}
+
+// nested adds the errors of a section's own validation
+func (v *validator) nested(path string, err error) {
+	if err == nil {
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			v.add(path, e.Error())
+		}
+		return
+	}
+	v.add(path, err.Error())
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReloadFunc applies a newly loaded configuration. Returning an error
+// rejects it and keeps the current configuration active.
+type ReloadFunc func(*Config) error
+
+// errEmptyFile rejects a config file without content on reload. It is most
+// likely being rewritten, and applying it would revert every setting.
+var errEmptyFile = errors.New("config file is empty")
+
+// Watcher reloads the configuration file when its content changes and
+// whenever Reload is called, e.g. on SIGHUP. A changed file is applied once
+// two polls in a row have seen the same content, so a file that is still
+// being written is not picked up half-way.
+type Watcher struct {
+	loader   *Loader
+	interval time.Duration
+	apply    ReloadFunc
+	log      *slog.Logger
+
+	// mu serializes reloads; checksum is the content last seen and pending
+	// the changed content seen by the previous poll
+	mu       sync.Mutex
+	checksum [sha256.Size]byte
+	pending  [sha256.Size]byte
+	trigger  chan struct{}
+}
+
+// NewWatcher creates a watcher for the config file of the loader, which
+// checks the file every interval. A zero interval disables polling so only
+// explicit reloads apply changes. Reloads keep the loader's environment and
+// command-line overrides.
+func NewWatcher(loader *Loader, interval time.Duration, apply ReloadFunc, log *slog.Logger) *Watcher {
+	w := &Watcher{
+		loader:   loader,
+		interval: interval,
+		apply:    apply,
+		log:      log,
+		trigger:  make(chan struct{}, 1),
+	}
+	// The file was just loaded at startup, so only later edits count
+	if data, err := os.ReadFile(loader.Path); err == nil {
+		w.checksum = sha256.Sum256(data)
+	}
+	return w
+}
+
+// Run watches the file until the context is done
+func (w *Watcher) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			w.reload(false)
+		case <-w.trigger:
+			w.reload(true)
+		}
+	}
+}
+
+// Trigger asks the running watcher to reload the file even if it hasn't
+// changed. It doesn't block.
+func (w *Watcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Reload loads the file and applies it right away, whether it changed or
+// not. The error tells why the new configuration was rejected.
+func (w *Watcher) Reload() error {
+	return w.reload(true)
+}
+
+// reload loads and applies the file if it changed and the previous poll saw
+// the same content, or if force is set
+func (w *Watcher) reload(force bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.loader.Path)
+	if err != nil {
+		w.log.Error("config reload failed, keeping the current configuration",
+			slog.String("path", w.loader.Path),
+			slog.String("error", err.Error()))
+		return err
+	}
+	checksum := sha256.Sum256(data)
+	if !force && bytes.Equal(checksum[:], w.checksum[:]) {
+		w.pending = checksum
+		return nil
+	}
+	if !force && !bytes.Equal(checksum[:], w.pending[:]) {
+		// Wait for the next poll to see the same content
+		w.pending = checksum
+		return nil
+	}
+	// A broken file is reported once, not on every poll
+	w.checksum = checksum
+
+	var config *Config
+	if len(bytes.TrimSpace(data)) == 0 {
+		err = errEmptyFile
+	} else {
+		config, err = w.loader.parse(data)
+	}
+	if err == nil {
+		err = w.apply(config)
+	}
+	if err != nil {
+		w.log.Error("config reload rejected, keeping the current configuration",
+			slog.String("path", w.loader.Path),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	w.log.Info("config reloaded", slog.String("path", w.loader.Path))
+	return nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"home24/pkg/logger"
+)
+
+// This struct is the body of the log level endpoints
+type logLevelBody struct {
+	Level string `json:"level"`
+}
+
+// This handler returns the current log level
+func (r *Router) logLevelHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, logLevelBody{Level: logger.LevelName(r.logs.Level())})
+}
+
+// This handler changes the log level until the process restarts or the
+// level in the config file changes
+func (r *Router) setLogLevelHandler(w http.ResponseWriter, req *http.Request) {
+	var body logLevelBody
+	var err = decodeJSON(w, req, &body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	var level, parseErr = logger.ParseLevel(body.Level)
+	if parseErr != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, parseErr.Error())
+		return
+	}
+
+	var previous = r.logs.Level()
+	r.logs.SetLevel(level)
+	// Logged as a warning so the change shows up at every level but error
+	r.log.WarnContext(req.Context(), "log level changed",
+		slog.String("from", logger.LevelName(previous)),
+		slog.String("to", logger.LevelName(level)))
+
+	writeJSON(w, http.StatusOK, logLevelBody{Level: logger.LevelName(level)})
+}
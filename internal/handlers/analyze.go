@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/url"
+
+	"home24/internal/analyzer"
 )
 
 // This function handles the form submission to analyze webpages
@@ -18,7 +21,7 @@ func (r *Router) analyzeHandler(w http.ResponseWriter, req *http.Request) {
 	var err = req.ParseForm()
 	if err != nil {
 		// Log the error for debugging
-		r.log.Error("error parsing form", slog.String("error", err.Error()))
+		r.log.ErrorContext(req.Context(), "error parsing form", slog.String("error", err.Error()))
 
 		// Send back a bad request response
 		http.Error(w, "Bad Request", 400) // 400 is http.StatusBadRequest
@@ -35,7 +38,7 @@ func (r *Router) analyzeHandler(w http.ResponseWriter, req *http.Request) {
 
 		err = r.tmpl.ExecuteTemplate(w, "index.html", templateData)
 		if err != nil {
-			r.log.Error("error rendering template", slog.String("error", err.Error()))
+			r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
 			http.Error(w, "Internal Server Error", 500) // 500 is http.StatusInternalServerError
 		}
 		return
@@ -53,17 +56,37 @@ func (r *Router) analyzeHandler(w http.ResponseWriter, req *http.Request) {
 
 		err = r.tmpl.ExecuteTemplate(w, "index.html", templateData)
 		if err != nil {
-			r.log.Error("error rendering template", slog.String("error", err.Error()))
+			r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+			http.Error(w, "Internal Server Error", 500) // 500 is http.StatusInternalServerError
+		}
+		return
+	}
+
+	// Read the optional headers, cookies and authentication for this analysis
+	var ctx = req.Context()
+	var creds, credsErr = credentialsFromForm(req.PostForm)
+	if credsErr != nil {
+		var templateData = map[string]interface{}{
+			"Error": credsErr.Error(),
+			"URL":   urlString,
+		}
+
+		err = r.tmpl.ExecuteTemplate(w, "index.html", templateData)
+		if err != nil {
+			r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
 			http.Error(w, "Internal Server Error", 500) // 500 is http.StatusInternalServerError
 		}
 		return
 	}
+	if creds != nil {
+		ctx = analyzer.WithCredentials(ctx, *creds)
+	}
 
 	// Call the analyzer to analyze the webpage
-	var result, analyzeErr = r.analyzer.Analyze(req.Context(), urlString)
+	var result, analyzeErr = r.analyzer.Analyze(ctx, urlString)
 	if analyzeErr != nil {
 		// If analysis fails, log the error and show it to the user
-		r.log.Error("error analyzing page",
+		r.log.ErrorContext(req.Context(), "error analyzing page",
 			slog.String("url", urlString),
 			slog.String("error", analyzeErr.Error()),
 		)
@@ -72,10 +95,15 @@ func (r *Router) analyzeHandler(w http.ResponseWriter, req *http.Request) {
 			"Error": analyzeErr.Error(),
 			"URL":   urlString,
 		}
+		// Busy servers answer with 503 so clients and proxies can back off
+		var analysisErr *analyzer.AnalysisError
+		if errors.As(analyzeErr, &analysisErr) && analysisErr.Code == analyzer.ErrOverloaded {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
 
 		err = r.tmpl.ExecuteTemplate(w, "index.html", templateData)
 		if err != nil {
-			r.log.Error("error rendering template", slog.String("error", err.Error()))
+			r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
 			http.Error(w, "Internal Server Error", 500) // 500 is http.StatusInternalServerError
 		}
 		return
@@ -89,7 +117,7 @@ func (r *Router) analyzeHandler(w http.ResponseWriter, req *http.Request) {
 
 	err = r.tmpl.ExecuteTemplate(w, "result.html", templateData)
 	if err != nil {
-		r.log.Error("error rendering template", slog.String("error", err.Error()))
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
 		http.Error(w, "Internal Server Error", 500) // 500 is http.StatusInternalServerError
 	}
 }
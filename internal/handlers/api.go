@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"home24/internal/analyzer"
+)
+
+// Error codes that are produced by the API itself rather than the analyzer
+const (
+	errCodeInvalidRequest = "INVALID_REQUEST"
+	errCodeNotFound       = "NOT_FOUND"
+	errCodeInternal       = "INTERNAL_ERROR"
+)
+
+// The non-standard status used when an analysis was canceled by the client
+const statusClientClosedRequest = 499
+
+// The maximum size of a JSON request body
+const maxRequestBodyBytes = 1 << 20
+
+// This struct is the JSON body of an analyze request
+type analyzeRequest struct {
+	URL         string                `json:"url"`
+	Credentials *analyzer.Credentials `json:"credentials,omitempty"`
+}
+
+// This struct is the JSON body of every error response
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// This struct describes an error in a machine-readable way
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// This handler runs an analysis and returns the result as JSON
+func (r *Router) apiAnalyzeHandler(w http.ResponseWriter, req *http.Request) {
+	var body analyzeRequest
+	var err = decodeJSON(w, req, &body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	err = validateTargetURL(body.URL)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, analyzer.ErrInvalidURL, err.Error())
+		return
+	}
+
+	var ctx = req.Context()
+	if body.Credentials != nil {
+		ctx = analyzer.WithCredentials(ctx, *body.Credentials)
+	}
+
+	var result, analyzeErr = r.analyzer.Analyze(ctx, body.URL)
+	if analyzeErr != nil {
+		r.log.ErrorContext(req.Context(), "error analyzing page",
+			slog.String("url", body.URL),
+			slog.String("error", analyzeErr.Error()),
+		)
+		writeAnalysisError(w, analyzeErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// This handler answers unknown API routes with a JSON error instead of the HTML 404 page
+func (r *Router) apiNotFoundHandler(w http.ResponseWriter, req *http.Request) {
+	writeAPIError(w, http.StatusNotFound, errCodeNotFound, "no API route for "+req.Method+" "+req.URL.Path)
+}
+
+// This function decodes a JSON request body and rejects unknown fields
+func decodeJSON(w http.ResponseWriter, req *http.Request, v interface{}) error {
+	var decoder = json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// This function checks that a target URL is an absolute http or https URL
+func validateTargetURL(urlString string) error {
+	if urlString == "" {
+		return errors.New("URL cannot be empty")
+	}
+	var parsedURL, err = url.Parse(urlString)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return errors.New("invalid URL, expected an absolute URL starting with http:// or https://")
+	}
+	return nil
+}
+
+// This function maps an analyzer error code to an HTTP status
+func statusForErrorCode(code string) int {
+	switch code {
+	case analyzer.ErrInvalidURL, analyzer.ErrInvalidCredentials, analyzer.ErrBlockedAddress:
+		return http.StatusBadRequest
+	case analyzer.ErrFetchFailed:
+		return http.StatusBadGateway
+	case analyzer.ErrTimeout:
+		return http.StatusGatewayTimeout
+	case analyzer.ErrOverloaded:
+		return http.StatusServiceUnavailable
+	case analyzer.ErrCanceled:
+		return statusClientClosedRequest
+	case analyzer.ErrParseFailed, analyzer.ErrMaxLinksReached, analyzer.ErrMaxDepthReached:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// This function writes an analysis error with the matching HTTP status
+func writeAnalysisError(w http.ResponseWriter, err error) {
+	var analysisErr *analyzer.AnalysisError
+	if errors.As(err, &analysisErr) {
+		var message = analysisErr.Message
+		if analysisErr.Err != nil {
+			message += ": " + analysisErr.Err.Error()
+		}
+		writeAPIError(w, statusForErrorCode(analysisErr.Code), analysisErr.Code, message)
+		return
+	}
+	writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
+}
+
+// This function writes a machine-readable error body
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: apiError{Code: code, Message: message}})
+}
+
+// This function writes a value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
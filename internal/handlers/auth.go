@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"home24/internal/auth"
+	"home24/internal/jobs"
+	"home24/pkg/logger"
+)
+
+// Error codes of requests that aren't authorized
+const (
+	errCodeUnauthorized = "UNAUTHORIZED"
+	errCodeForbidden    = "FORBIDDEN"
+)
+
+// The realm browsers show when they ask for the API key
+const authRealm = `Basic realm="webpage-analyzer", charset="UTF-8"`
+
+// This function wraps a handler so it requires an API key with all of the
+// given scopes; without scopes any valid key will do. The request context
+// carries the identity of the key and its log lines the key's name. Without
+// an authenticator every request is let through.
+func (r *Router) requireScope(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	if r.auth == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		var identity, err = r.auth.Authenticate(req.Context(), auth.KeyFromRequest(req))
+		if errors.Is(err, auth.ErrMissingKey) || errors.Is(err, auth.ErrInvalidKey) {
+			if errors.Is(err, auth.ErrInvalidKey) {
+				r.log.WarnContext(req.Context(), "request with an invalid API key", slog.String("path", req.URL.Path))
+			}
+			writeAuthError(w, req, http.StatusUnauthorized, errCodeUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			r.log.ErrorContext(req.Context(), "error authenticating request", slog.String("error", err.Error()))
+			writeAuthError(w, req, http.StatusInternalServerError, errCodeInternal, "internal error")
+			return
+		}
+
+		var ctx = auth.WithIdentity(req.Context(), identity)
+		ctx = logger.WithAttrs(ctx, slog.String("api_key", identity.Name))
+		req = req.WithContext(ctx)
+
+		for _, scope := range scopes {
+			if !identity.Allows(scope) {
+				r.log.WarnContext(ctx, "request without the required scope",
+					slog.String("path", req.URL.Path),
+					slog.String("scope", scope),
+				)
+				writeAuthError(w, req, http.StatusForbidden, errCodeForbidden, "the API key needs the "+scope+" scope")
+				return
+			}
+		}
+		next(w, req)
+	}
+}
+
+// This function wraps a handler so it requires an API key with at least one
+// of the given scopes
+func (r *Router) requireAnyScope(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return r.requireScope(func(w http.ResponseWriter, req *http.Request) {
+		for _, scope := range scopes {
+			if r.allows(req, scope) {
+				next(w, req)
+				return
+			}
+		}
+		r.log.WarnContext(req.Context(), "request without any of the required scopes",
+			slog.String("path", req.URL.Path),
+			slog.String("scopes", strings.Join(scopes, ",")),
+		)
+		writeAuthError(w, req, http.StatusForbidden, errCodeForbidden, "the API key needs one of the "+strings.Join(scopes, ", ")+" scopes")
+	})
+}
+
+// This function reports whether the caller may see a job: the key that
+// submitted it and admin keys may. Without an authenticator everyone may.
+func (r *Router) ownsJob(req *http.Request, job jobs.Job) bool {
+	if r.auth == nil {
+		return true
+	}
+	var identity = auth.FromContext(req.Context())
+	if identity == nil {
+		return false
+	}
+	if identity.Allows(auth.ScopeAdmin) {
+		return true
+	}
+	return job.Owner != nil && job.Owner.Name == identity.Name && job.Owner.KeyID == identity.KeyID
+}
+
+// This function reports whether the caller may use a scope. Without an
+// authenticator everything is allowed.
+func (r *Router) allows(req *http.Request, scope string) bool {
+	if r.auth == nil {
+		return true
+	}
+	var identity = auth.FromContext(req.Context())
+	return identity != nil && identity.Allows(scope)
+}
+
+// This function writes an authentication error as JSON for the API and as
+// text for the pages. A missing key is asked for as the password of basic
+// authentication, also on the API as the pages call it from the browser.
+func writeAuthError(w http.ResponseWriter, req *http.Request, status int, code, message string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", authRealm)
+	}
+	if strings.HasPrefix(req.URL.Path, "/api/") {
+		writeAPIError(w, status, code, message)
+		return
+	}
+	http.Error(w, message, status)
+}
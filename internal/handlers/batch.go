@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"home24/internal/analyzer"
+	"home24/internal/batch"
+	"home24/internal/jobs"
+	"home24/internal/webhook"
+)
+
+// The job kind for batch analyses
+const jobKindBatch = "batch"
+
+// The maximum size of an uploaded URL list
+const maxUploadBytes = 10 << 20
+
+// This struct is the JSON body of a batch request
+type batchRequest struct {
+	URLs        []string              `json:"urls"`
+	Credentials *analyzer.Credentials `json:"credentials,omitempty"`
+	Webhooks    []webhook.Endpoint    `json:"webhooks,omitempty"`
+}
+
+// This handler shows the batch analysis form
+func (r *Router) batchFormHandler(w http.ResponseWriter, req *http.Request) {
+	r.renderBatchForm(w, req, "", "")
+}
+
+// This handler starts a batch analysis from the form. URLs can be typed into
+// the textarea, uploaded as a file, or both.
+func (r *Router) batchSubmitHandler(w http.ResponseWriter, req *http.Request) {
+	var err = req.ParseMultipartForm(maxUploadBytes)
+	if err != nil && err != http.ErrNotMultipart {
+		r.renderBatchForm(w, req, "Could not read the form: "+err.Error(), "")
+		return
+	}
+
+	var list = req.FormValue("urls")
+	var file, _, fileErr = req.FormFile("file")
+	if fileErr == nil {
+		defer file.Close()
+		var data, readErr = io.ReadAll(io.LimitReader(file, maxUploadBytes))
+		if readErr != nil {
+			r.renderBatchForm(w, req, "Could not read the uploaded file: "+readErr.Error(), list)
+			return
+		}
+		list += "\n" + string(data)
+	}
+
+	var urls, parseErr = r.parseBatchURLs(strings.NewReader(list))
+	if parseErr != nil {
+		r.renderBatchForm(w, req, parseErr.Error(), list)
+		return
+	}
+
+	var creds, credsErr = credentialsFromForm(req.Form)
+	if credsErr != nil {
+		r.renderBatchForm(w, req, credsErr.Error(), list)
+		return
+	}
+
+	var job, submitErr = r.submitBatchJob(req.Context(), urls, creds, nil)
+	if submitErr != nil {
+		r.log.ErrorContext(req.Context(), "error submitting batch job", slog.String("error", submitErr.Error()))
+		r.renderBatchForm(w, req, "The analyzer is busy, please try again in a moment.", list)
+		return
+	}
+
+	http.Redirect(w, req, "/batch/"+job.ID, http.StatusSeeOther)
+}
+
+// This handler shows the progress of a batch job and its report once it has finished
+func (r *Router) batchReportHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil || job.Kind != jobKindBatch {
+		r.notFoundHandler(w, req)
+		return
+	}
+
+	var report, _ = job.Result.(*batch.Report)
+	err = r.tmpl.ExecuteTemplate(w, "batch_report.html", map[string]interface{}{
+		"Job":    job,
+		"Report": report,
+	})
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// This handler starts a batch analysis through the API. The URLs are sent
+// either as JSON or as a plain text list with one URL per line.
+func (r *Router) apiSubmitBatchHandler(w http.ResponseWriter, req *http.Request) {
+	var body batchRequest
+	var urls []string
+	var err error
+
+	var mediaType, _, _ = mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "text/plain" {
+		urls, err = r.parseBatchURLs(http.MaxBytesReader(w, req.Body, maxUploadBytes))
+	} else {
+		err = decodeJSON(w, req, &body)
+		if err == nil {
+			err = r.validateWebhooks(req.Context(), body.Webhooks)
+		}
+		if err == nil {
+			urls, err = r.parseBatchURLs(strings.NewReader(strings.Join(body.URLs, "\n")))
+		}
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, requestErrorCode(err), err.Error())
+		return
+	}
+
+	var job, submitErr = r.submitBatchJob(req.Context(), urls, body.Credentials, body.Webhooks)
+	if submitErr != nil {
+		writeJobError(w, submitErr)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// This function parses a URL list and applies the batch size limit
+func (r *Router) parseBatchURLs(reader io.Reader) ([]string, error) {
+	var urls, err = batch.ParseURLList(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no URLs given")
+	}
+	if r.batch.MaxURLs > 0 && len(urls) > r.batch.MaxURLs {
+		return nil, fmt.Errorf("too many URLs: %d given, at most %d allowed", len(urls), r.batch.MaxURLs)
+	}
+	return urls, nil
+}
+
+// This function queues a batch analysis as a job
+func (r *Router) submitBatchJob(ctx context.Context, urls []string, creds *analyzer.Credentials, webhooks []webhook.Endpoint) (jobs.Job, error) {
+	var target = fmt.Sprintf("%d URLs", len(urls))
+	return r.submitJob(ctx, jobKindBatch, target, jobParams{URLs: urls, Credentials: creds, Webhooks: webhooks})
+}
+
+// This function builds the task of a batch job from its parameters
+func (r *Router) buildBatchJob(raw json.RawMessage) (jobs.Task, []jobs.FinishHook, error) {
+	var params jobParams
+	var err = json.Unmarshal(raw, &params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var task = func(ctx context.Context) (interface{}, error) {
+		if params.Credentials != nil {
+			ctx = analyzer.WithCredentials(ctx, *params.Credentials)
+		}
+		var report = batch.Run(ctx, r.analyzer, params.URLs, r.batch.Concurrency)
+		if ctx.Err() != nil {
+			return nil, analyzer.NewAnalysisError(analyzer.ErrCanceled, "batch canceled", ctx.Err())
+		}
+		return report, nil
+	}
+	return task, r.finishHooks(params.Webhooks), nil
+}
+
+// This function shows the batch form with an optional error message
+func (r *Router) renderBatchForm(w http.ResponseWriter, req *http.Request, message, list string) {
+	var err = r.tmpl.ExecuteTemplate(w, "batch.html", map[string]interface{}{
+		"Error":   message,
+		"URLs":    list,
+		"MaxURLs": r.batch.MaxURLs,
+	})
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/compare"
+	"home24/internal/store"
+)
+
+// The heading levels shown side by side
+var headingLevels = []string{"h1", "h2", "h3", "h4", "h5", "h6"}
+
+// The error returned when no analysis was chosen to compare with
+var errNoComparison = errors.New("choose the analysis to compare with")
+
+// The error returned when the analyses to compare are of different pages
+var errDifferentPages = errors.New("the analyses are of different pages")
+
+// This struct is the body of a comparison request. Without an after ID the
+// page of the before analysis is analyzed again.
+type compareRequest struct {
+	Before string `json:"before"`
+	After  string `json:"after,omitempty"`
+}
+
+// This struct is the comparison returned by the API
+type compareResponse struct {
+	Before store.Summary `json:"before"`
+	After  store.Summary `json:"after"`
+	Live   bool          `json:"live"`
+	Diff   *compare.Diff `json:"diff"`
+}
+
+// This handler compares two stored analyses side by side
+func (r *Router) compareHandler(w http.ResponseWriter, req *http.Request) {
+	var query = req.URL.Query()
+	var before, after, err = r.loadComparison(req.Context(), query.Get("before"), query.Get("after"))
+	r.renderComparison(w, req, before, after, false, err)
+}
+
+// This handler analyzes the page of a stored analysis again and compares
+// the two
+func (r *Router) compareLiveHandler(w http.ResponseWriter, req *http.Request) {
+	var err = req.ParseForm()
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error parsing form", slog.String("error", err.Error()))
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var before, after, compareErr = r.loadComparison(req.Context(), req.PostForm.Get("before"), "")
+	r.renderComparison(w, req, before, after, true, compareErr)
+}
+
+// This handler compares two analyses through the API
+func (r *Router) apiCompareHandler(w http.ResponseWriter, req *http.Request) {
+	var body compareRequest
+	var err = decodeJSON(w, req, &body)
+	if err == nil && strings.TrimSpace(body.Before) == "" {
+		err = errNoComparison
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Comparing with a new analysis runs one
+	if body.After == "" && !r.allows(req, auth.ScopeAnalyze) {
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, "the API key needs the "+auth.ScopeAnalyze+" scope to compare with a new analysis")
+		return
+	}
+
+	var before, after, compareErr = r.loadComparison(req.Context(), body.Before, body.After)
+	if errors.Is(compareErr, errDifferentPages) {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, compareErr.Error())
+		return
+	}
+	if errors.Is(compareErr, store.ErrNotFound) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, compareErr.Error())
+		return
+	}
+	var analysisErr *analyzer.AnalysisError
+	if errors.As(compareErr, &analysisErr) {
+		writeAnalysisError(w, compareErr)
+		return
+	}
+	if compareErr != nil {
+		r.log.ErrorContext(req.Context(), "error comparing analyses", slog.String("error", compareErr.Error()))
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compareResponse{
+		Before: before.Summary(),
+		After:  after.Summary(),
+		Live:   body.After == "",
+		Diff:   compare.Compare(before.Result, after.Result),
+	})
+}
+
+// This function loads the analyses to compare. If afterID is empty the page
+// of the before analysis is analyzed again; the new run is saved to the
+// history like any other. Analyses of different pages are refused.
+func (r *Router) loadComparison(ctx context.Context, beforeID, afterID string) (*store.Record, *store.Record, error) {
+	if beforeID == "" {
+		return nil, nil, errNoComparison
+	}
+	var before, err = r.results.Get(ctx, beforeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var after *store.Record
+	if afterID == "" {
+		after, err = r.recorder.Record(ctx, before.URL)
+	} else {
+		after, err = r.results.Get(ctx, afterID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if before.URL != after.URL {
+		return nil, nil, errDifferentPages
+	}
+	return before, after, nil
+}
+
+// This function shows two analyses side by side with their differences, or
+// the error that prevented the comparison
+func (r *Router) renderComparison(w http.ResponseWriter, req *http.Request, before, after *store.Record, live bool, err error) {
+	var templateData = map[string]interface{}{}
+	var analysisErr *analyzer.AnalysisError
+	switch {
+	case err == nil:
+		templateData["Before"] = before
+		templateData["After"] = after
+		templateData["Live"] = live
+		templateData["Levels"] = headingLevels
+		templateData["Diff"] = compare.Compare(before.Result, after.Result)
+	case errors.Is(err, errNoComparison):
+		w.WriteHeader(http.StatusBadRequest)
+		templateData["Error"] = "Choose the analyses to compare in the history."
+	case errors.Is(err, errDifferentPages):
+		w.WriteHeader(http.StatusBadRequest)
+		templateData["Error"] = "Only analyses of the same page can be compared."
+	case errors.Is(err, store.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		templateData["Error"] = "One of the analyses to compare no longer exists."
+	case errors.As(err, &analysisErr):
+		w.WriteHeader(statusForErrorCode(analysisErr.Code))
+		templateData["Error"] = analysisErr.Message
+	default:
+		r.log.ErrorContext(req.Context(), "error comparing analyses", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		templateData["Error"] = "The analyses could not be compared, please try again."
+	}
+
+	var tmplErr = r.tmpl.ExecuteTemplate(w, "compare.html", templateData)
+	if tmplErr != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", tmplErr.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"home24/internal/analyzer"
+)
+
+// This function reads the optional request settings from the analyze form.
+// It returns nil when none of the fields were filled in.
+func credentialsFromForm(form url.Values) (*analyzer.Credentials, error) {
+	var creds = &analyzer.Credentials{}
+	var isSet = false
+
+	// Hosts are separated by commas or whitespace
+	for _, host := range strings.FieldsFunc(form.Get("hosts"), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		creds.Hosts = append(creds.Hosts, host)
+	}
+
+	// Headers are given one per line as "Name: value"
+	for _, line := range nonEmptyLines(form.Get("headers")) {
+		var name, value, found = strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", line)
+		}
+		if creds.Headers == nil {
+			creds.Headers = make(map[string]string)
+		}
+		creds.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		isSet = true
+	}
+
+	// Cookies are given one per line as "name=value"
+	for _, line := range nonEmptyLines(form.Get("cookies")) {
+		var name, value, found = strings.Cut(line, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid cookie %q, expected \"name=value\"", line)
+		}
+		if creds.Cookies == nil {
+			creds.Cookies = make(map[string]string)
+		}
+		creds.Cookies[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		isSet = true
+	}
+
+	if username := form.Get("username"); username != "" {
+		creds.BasicAuth = &analyzer.BasicAuth{
+			Username: username,
+			Password: form.Get("password"),
+		}
+		isSet = true
+	}
+
+	if token := strings.TrimSpace(form.Get("bearer_token")); token != "" {
+		creds.BearerToken = token
+		isSet = true
+	}
+
+	var certPEM = strings.TrimSpace(form.Get("client_cert"))
+	var keyPEM = strings.TrimSpace(form.Get("client_key"))
+	if certPEM != "" || keyPEM != "" {
+		creds.ClientCert = &analyzer.ClientCertificate{
+			CertPEM: certPEM,
+			KeyPEM:  keyPEM,
+		}
+		isSet = true
+	}
+
+	if !isSet {
+		return nil, nil
+	}
+	return creds, nil
+}
+
+// This function splits text into trimmed lines and drops the empty ones
+func nonEmptyLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
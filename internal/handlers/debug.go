@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/pkg/logger"
+)
+
+// The time the process started, for the uptime in the runtime statistics
+var processStart = time.Now()
+
+// This struct is the body of the runtime statistics endpoint
+type runtimeStats struct {
+	GoVersion  string        `json:"go_version"`
+	Uptime     time.Duration `json:"uptime_ns"`
+	Goroutines int           `json:"goroutines"`
+	GOMAXPROCS int           `json:"gomaxprocs"`
+	NumCPU     int           `json:"num_cpu"`
+	Heap       heapStats     `json:"heap"`
+	GC         gcStats       `json:"gc"`
+}
+
+// This struct describes the heap, in bytes unless noted otherwise
+type heapStats struct {
+	Alloc      uint64 `json:"alloc_bytes"`
+	InUse      uint64 `json:"in_use_bytes"`
+	Idle       uint64 `json:"idle_bytes"`
+	Released   uint64 `json:"released_bytes"`
+	Sys        uint64 `json:"sys_bytes"`
+	Objects    uint64 `json:"objects"`
+	NextGC     uint64 `json:"next_gc_bytes"`
+	TotalAlloc uint64 `json:"total_alloc_bytes"`
+}
+
+// This struct describes the garbage collector's work
+type gcStats struct {
+	Cycles      uint32        `json:"cycles"`
+	Forced      uint32        `json:"forced"`
+	LastRun     *time.Time    `json:"last_run,omitempty"`
+	LastPause   time.Duration `json:"last_pause_ns"`
+	TotalPause  time.Duration `json:"total_pause_ns"`
+	CPUFraction float64       `json:"cpu_fraction"`
+}
+
+// This function creates the handler of the admin listener: the Go profiler
+// under /debug/pprof/, runtime statistics at /debug/runtime, the analyses in
+// flight and the state of the analyzer's limits and connections at
+// /debug/analyzer, and the log level at /admin/log-level. With an
+// authenticator every route needs a key with the admin scope.
+func NewAdminRouter(logs *logger.Logger, pageAnalyzer *analyzer.DefaultPageAnalyzer, authenticator *auth.Authenticator) http.Handler {
+	var router = &Router{
+		log:          logs.Logger,
+		logs:         logs,
+		auth:         authenticator,
+		pageAnalyzer: pageAnalyzer,
+	}
+
+	var mux = http.NewServeMux()
+
+	// Register the profiler; the index also serves the named profiles such
+	// as /debug/pprof/heap and /debug/pprof/goroutine
+	mux.HandleFunc("GET /debug/pprof/", router.requireScope(pprof.Index, auth.ScopeAdmin))
+	mux.HandleFunc("GET /debug/pprof/cmdline", router.requireScope(pprof.Cmdline, auth.ScopeAdmin))
+	mux.HandleFunc("GET /debug/pprof/profile", router.requireScope(pprof.Profile, auth.ScopeAdmin))
+	mux.HandleFunc("GET /debug/pprof/symbol", router.requireScope(pprof.Symbol, auth.ScopeAdmin))
+	mux.HandleFunc("POST /debug/pprof/symbol", router.requireScope(pprof.Symbol, auth.ScopeAdmin))
+	mux.HandleFunc("GET /debug/pprof/trace", router.requireScope(pprof.Trace, auth.ScopeAdmin))
+
+	mux.HandleFunc("GET /debug/runtime", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.runtimeStatsHandler(w, r)
+	}, auth.ScopeAdmin))
+
+	mux.HandleFunc("GET /debug/analyzer", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.analyzerStateHandler(w, r)
+	}, auth.ScopeAdmin))
+
+	mux.HandleFunc("GET /admin/log-level", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.logLevelHandler(w, r)
+	}, auth.ScopeAdmin))
+
+	mux.HandleFunc("PUT /admin/log-level", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.setLogLevelHandler(w, r)
+	}, auth.ScopeAdmin))
+
+	return withRequestID(mux)
+}
+
+// This handler returns the goroutine count and the heap and garbage
+// collector statistics. Reading them briefly stops the world.
+func (r *Router) runtimeStatsHandler(w http.ResponseWriter, req *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var stats = runtimeStats{
+		GoVersion:  runtime.Version(),
+		Uptime:     time.Since(processStart),
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Heap: heapStats{
+			Alloc:      mem.HeapAlloc,
+			InUse:      mem.HeapInuse,
+			Idle:       mem.HeapIdle,
+			Released:   mem.HeapReleased,
+			Sys:        mem.HeapSys,
+			Objects:    mem.HeapObjects,
+			NextGC:     mem.NextGC,
+			TotalAlloc: mem.TotalAlloc,
+		},
+		GC: gcStats{
+			Cycles:      mem.NumGC,
+			Forced:      mem.NumForcedGC,
+			TotalPause:  time.Duration(mem.PauseTotalNs),
+			CPUFraction: mem.GCCPUFraction,
+		},
+	}
+	if mem.NumGC > 0 {
+		var lastRun = time.Unix(0, int64(mem.LastGC))
+		stats.GC.LastRun = &lastRun
+		stats.GC.LastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// This handler returns the analyses that are waiting or running with their
+// targets and ages, and the use of the analysis, outbound request and
+// connection limits
+func (r *Router) analyzerStateHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.pageAnalyzer.DebugState())
+}
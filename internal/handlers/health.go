@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"home24/internal/version"
+)
+
+// How long all readiness checks together may take
+const readinessTimeout = 2 * time.Second
+
+// The templates every page needs
+var requiredTemplates = []string{"index.html", "result.html", "404.html"}
+
+// This struct is a named dependency checked by the readiness endpoint
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// This struct is the body of the readiness endpoint
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// This handler reports that the process is alive. It checks nothing else so
+// a busy or degraded instance isn't restarted.
+func (r *Router) healthzHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// This handler reports whether the instance can serve requests: the
+// templates are loaded, the configuration is valid and the job queue and
+// history store are reachable
+func (r *Router) readyzHandler(w http.ResponseWriter, req *http.Request) {
+	var ctx, cancel = context.WithTimeout(req.Context(), readinessTimeout)
+	defer cancel()
+
+	var response = readinessResponse{Status: "ready", Checks: map[string]string{}}
+	var status = http.StatusOK
+	for _, c := range r.checks {
+		var err = c.check(ctx)
+		if err != nil {
+			response.Checks[c.name] = err.Error()
+			response.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		response.Checks[c.name] = "ok"
+	}
+
+	writeJSON(w, status, response)
+}
+
+// This handler returns the version of the running build
+func (r *Router) versionHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
+
+// This function checks that the page templates are loaded
+func (r *Router) checkTemplates(ctx context.Context) error {
+	if r.tmpl == nil {
+		return errors.New("templates not loaded")
+	}
+	for _, name := range requiredTemplates {
+		if r.tmpl.Lookup(name) == nil {
+			return errors.New("template " + name + " not loaded")
+		}
+	}
+	return nil
+}
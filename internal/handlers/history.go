@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"home24/internal/store"
+)
+
+// The number of history entries on a page and the most a request can ask for
+const (
+	historyPageSize = 50
+	maxHistoryLimit = 200
+)
+
+// This handler lists past analyses with optional host and date filters
+func (r *Router) historyHandler(w http.ResponseWriter, req *http.Request) {
+	var query = req.URL.Query()
+	var filter, err = historyFilter(query)
+	if filter.Limit == 0 {
+		filter.Limit = historyPageSize
+	}
+	var items []store.Summary
+	if err == nil {
+		items, err = r.results.List(req.Context(), filter)
+		if err != nil {
+			r.log.ErrorContext(req.Context(), "error listing history", slog.String("error", err.Error()))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var templateData = map[string]interface{}{
+		"Items": items,
+		"Host":  query.Get("host"),
+		"From":  query.Get("from"),
+		"To":    query.Get("to"),
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templateData["Error"] = err.Error()
+	} else {
+		if filter.Offset > 0 {
+			templateData["PrevURL"] = historyPageURL(query, max(filter.Offset-filter.Limit, 0))
+		}
+		if len(items) == filter.Limit {
+			templateData["NextURL"] = historyPageURL(query, filter.Offset+filter.Limit)
+		}
+	}
+
+	err = r.tmpl.ExecuteTemplate(w, "history.html", templateData)
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// This handler reopens a past analysis on the result page
+func (r *Router) historyRecordHandler(w http.ResponseWriter, req *http.Request) {
+	var record, err = r.results.Get(req.Context(), req.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		r.notFoundHandler(w, req)
+		return
+	}
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error loading history record", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = r.tmpl.ExecuteTemplate(w, "result.html", map[string]interface{}{
+		"URL":    record.URL,
+		"Result": record.Result,
+		"Record": record,
+	})
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// This handler lists past analyses through the API
+func (r *Router) apiHistoryHandler(w http.ResponseWriter, req *http.Request) {
+	var filter, err = historyFilter(req.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	var items, listErr = r.results.List(req.Context(), filter)
+	if listErr != nil {
+		r.log.ErrorContext(req.Context(), "error listing history", slog.String("error", listErr.Error()))
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+// This handler returns a past analysis through the API
+func (r *Router) apiHistoryRecordHandler(w http.ResponseWriter, req *http.Request) {
+	var record, err = r.results.Get(req.Context(), req.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error loading history record", slog.String("error", err.Error()))
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// This function reads the history filter from the query string. Dates are
+// either RFC 3339 timestamps or days (YYYY-MM-DD); a day in "to" includes
+// the whole day.
+func historyFilter(query url.Values) (store.Filter, error) {
+	var filter = store.Filter{
+		Host: strings.TrimSpace(query.Get("host")),
+	}
+
+	var err error
+	filter.From, _, err = parseHistoryTime(query.Get("from"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	var to, day, toErr = parseHistoryTime(query.Get("to"))
+	if toErr != nil {
+		return filter, fmt.Errorf("invalid to: %w", toErr)
+	}
+	if day {
+		to = to.AddDate(0, 0, 1)
+	}
+	filter.To = to
+
+	filter.Limit, err = parseHistoryInt(query.Get("limit"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid limit: %w", err)
+	}
+	if filter.Limit > maxHistoryLimit {
+		return filter, fmt.Errorf("invalid limit: at most %d entries can be listed at once", maxHistoryLimit)
+	}
+	filter.Offset, err = parseHistoryInt(query.Get("offset"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid offset: %w", err)
+	}
+	return filter, nil
+}
+
+// This function parses a date filter and reports whether it was a whole day
+func parseHistoryTime(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	var t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("expected YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	return t, false, nil
+}
+
+// This function parses an optional non-negative number
+func parseHistoryInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	var n, err = strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New("expected a non-negative number")
+	}
+	return n, nil
+}
+
+// This function builds the link to another history page
+func historyPageURL(query url.Values, offset int) string {
+	var page = url.Values{}
+	for key, values := range query {
+		page[key] = values
+	}
+	page.Set("offset", strconv.Itoa(offset))
+	return "/history?" + page.Encode()
+}
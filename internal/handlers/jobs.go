@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/jobs"
+	"home24/internal/webhook"
+)
+
+// Error codes for the jobs API
+const (
+	errCodeJobNotFound    = "JOB_NOT_FOUND"
+	errCodeJobNotFinished = "JOB_NOT_FINISHED"
+	errCodeJobFinished    = "JOB_FINISHED"
+	errCodeQueueFull      = "QUEUE_FULL"
+)
+
+// The job kind for single page analyses
+const jobKindAnalysis = "analysis"
+
+// The scopes of which the job routes need one, besides the job being the
+// caller's own
+var jobReadScopes = []string{auth.ScopeAnalyze, auth.ScopeCrawl, auth.ScopeReadHistory}
+
+// This struct is the JSON body of a job request. The webhooks are notified
+// when the job finishes, in addition to the configured ones.
+type jobRequest struct {
+	analyzeRequest
+	Webhooks []webhook.Endpoint `json:"webhooks,omitempty"`
+}
+
+// This handler submits an analysis job and returns its ID straight away
+func (r *Router) apiSubmitJobHandler(w http.ResponseWriter, req *http.Request) {
+	var body jobRequest
+	var err = decodeJSON(w, req, &body)
+	if err == nil {
+		err = r.validateWebhooks(req.Context(), body.Webhooks)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, requestErrorCode(err), err.Error())
+		return
+	}
+
+	err = validateTargetURL(body.URL)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, analyzer.ErrInvalidURL, err.Error())
+		return
+	}
+
+	var job, submitErr = r.submitAnalysisJob(req.Context(), body.URL, body.Credentials, body.Webhooks)
+	if submitErr != nil {
+		writeJobError(w, submitErr)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// This struct holds the parameters of a job. They are saved when the
+// server shuts down before the job has finished, so it can be resumed.
+type jobParams struct {
+	URL         string                `json:"url,omitempty"`
+	URLs        []string              `json:"urls,omitempty"`
+	Credentials *analyzer.Credentials `json:"credentials,omitempty"`
+	Webhooks    []webhook.Endpoint    `json:"webhooks,omitempty"`
+}
+
+// This function queues a job built from its parameters. Jobs with request
+// credentials or webhooks, which carry secrets, aren't resumed after a
+// restart so the secrets are never written to the checkpoint file.
+func (r *Router) submitJob(ctx context.Context, kind, target string, params jobParams) (jobs.Job, error) {
+	if params.Credentials != nil || len(params.Webhooks) > 0 {
+		return r.jobs.SubmitUnsaved(ctx, kind, target, params)
+	}
+	return r.jobs.SubmitResumable(ctx, kind, target, params)
+}
+
+// This function queues an analysis of the URL as a job
+func (r *Router) submitAnalysisJob(ctx context.Context, urlString string, creds *analyzer.Credentials, webhooks []webhook.Endpoint) (jobs.Job, error) {
+	return r.submitJob(ctx, jobKindAnalysis, urlString, jobParams{URL: urlString, Credentials: creds, Webhooks: webhooks})
+}
+
+// This function builds the task of an analysis job from its parameters
+func (r *Router) buildAnalysisJob(raw json.RawMessage) (jobs.Task, []jobs.FinishHook, error) {
+	var params jobParams
+	var err = json.Unmarshal(raw, &params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var task = func(ctx context.Context) (interface{}, error) {
+		if params.Credentials != nil {
+			ctx = analyzer.WithCredentials(ctx, *params.Credentials)
+		}
+		return r.analyzer.Analyze(ctx, params.URL)
+	}
+	return task, r.finishHooks(params.Webhooks), nil
+}
+
+// This function returns the hooks that notify the configured webhooks and
+// the ones given with the request once a job finishes
+func (r *Router) finishHooks(webhooks []webhook.Endpoint) []jobs.FinishHook {
+	var hook = r.notifier.Hook(webhooks)
+	if hook == nil {
+		return nil
+	}
+	return []jobs.FinishHook{hook}
+}
+
+// This function checks the webhooks given with a request. Their URLs must
+// not lead to private or internal addresses.
+func (r *Router) validateWebhooks(ctx context.Context, webhooks []webhook.Endpoint) error {
+	for _, endpoint := range webhooks {
+		var err = r.notifier.CheckEndpoint(ctx, endpoint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// This function returns the error code for an invalid request body, which
+// is BLOCKED_ADDRESS for webhooks at blocked addresses
+func requestErrorCode(err error) string {
+	var blocked *analyzer.BlockedAddressError
+	if errors.As(err, &blocked) {
+		return analyzer.ErrBlockedAddress
+	}
+	return errCodeInvalidRequest
+}
+
+// This function returns the job named in the path. Jobs of other API keys
+// are not found, unless the caller is an admin.
+func (r *Router) callerJob(req *http.Request) (jobs.Job, error) {
+	var job, err = r.jobs.Get(req.PathValue("id"))
+	if err == nil && !r.ownsJob(req, job) {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return job, err
+}
+
+// This handler returns the status and progress of a job
+func (r *Router) apiGetJobHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// This handler returns the result of a finished job
+func (r *Router) apiJobResultHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	switch job.Status {
+	case jobs.StatusSucceeded:
+		writeJSON(w, http.StatusOK, job.Result)
+	case jobs.StatusFailed, jobs.StatusCanceled:
+		var jobErr = jobFailure(job)
+		writeAPIError(w, statusForErrorCode(jobErr.Code), jobErr.Code, jobErr.Message)
+	default:
+		writeJobError(w, jobs.ErrNotFinished)
+	}
+}
+
+// This handler cancels a queued or running job
+func (r *Router) apiCancelJobHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err == nil {
+		job, err = r.jobs.Cancel(job.ID)
+	}
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// This handler streams the events of a job as Server-Sent Events. Events the
+// job reported before the client connected are replayed first. Once the job
+// finishes a final "status" event carries the job itself.
+func (r *Router) apiJobEventsHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	var id = job.ID
+	history, events, unsubscribe, err := r.jobs.Subscribe(id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	defer unsubscribe()
+
+	// The stream outlives the server's write timeout, so lift it for this response
+	var controller = http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range history {
+		writeServerSentEvent(w, string(event.Type), event)
+	}
+	controller.Flush()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				var job, getErr = r.jobs.Get(id)
+				if getErr == nil {
+					writeServerSentEvent(w, "status", job)
+					controller.Flush()
+				}
+				return
+			}
+			writeServerSentEvent(w, string(event.Type), event)
+			controller.Flush()
+		}
+	}
+}
+
+// This function writes one Server-Sent Event with a JSON payload
+func writeServerSentEvent(w io.Writer, name string, v interface{}) {
+	var data, err = json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
+
+// This function returns why a job failed or was canceled
+func jobFailure(job jobs.Job) jobs.Error {
+	if job.Error != nil {
+		return *job.Error
+	}
+	if job.Status == jobs.StatusCanceled {
+		return jobs.Error{Code: analyzer.ErrCanceled, Message: "job canceled"}
+	}
+	return jobs.Error{Code: errCodeInternal, Message: "job failed"}
+}
+
+// This function maps job manager errors to API errors
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeAPIError(w, http.StatusNotFound, errCodeJobNotFound, err.Error())
+	case errors.Is(err, jobs.ErrNotFinished):
+		writeAPIError(w, http.StatusConflict, errCodeJobNotFinished, err.Error())
+	case errors.Is(err, jobs.ErrFinished):
+		writeAPIError(w, http.StatusConflict, errCodeJobFinished, err.Error())
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrClosed):
+		w.Header().Set("Retry-After", "5")
+		writeAPIError(w, http.StatusServiceUnavailable, errCodeQueueFull, err.Error())
+	default:
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"home24/internal/auth"
+)
+
+// The error code of unknown API keys
+const errCodeKeyNotFound = "KEY_NOT_FOUND"
+
+// This struct is the JSON body of a request to create an API key
+type createKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// This struct is the response to creating an API key. The key is only ever
+// returned here.
+type createKeyResponse struct {
+	auth.StoredKey
+	Key string `json:"key"`
+}
+
+// This struct describes an API key in listings. Configured keys have no ID
+// or creation time.
+type keyItem struct {
+	ID         string     `json:"id,omitempty"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Configured bool       `json:"configured"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// This handler lists the API keys without the keys themselves
+func (r *Router) apiListKeysHandler(w http.ResponseWriter, req *http.Request) {
+	var keys, err = r.auth.ListKeys(req.Context())
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error listing API keys", slog.String("error", err.Error()))
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
+		return
+	}
+
+	var items = []keyItem{}
+	for _, key := range keys {
+		var item = keyItem{ID: key.ID, Name: key.Name, Scopes: key.Scopes, Configured: key.ID == ""}
+		if !item.Configured {
+			item.CreatedAt = &key.CreatedAt
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+// This handler creates an API key and returns it once
+func (r *Router) apiCreateKeyHandler(w http.ResponseWriter, req *http.Request) {
+	var body createKeyRequest
+	var err = decodeJSON(w, req, &body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	var key, stored, createErr = r.auth.CreateKey(req.Context(), body.Name, body.Scopes)
+	if createErr != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, createErr.Error())
+		return
+	}
+
+	r.log.InfoContext(req.Context(), "API key created",
+		slog.String("key_id", stored.ID),
+		slog.String("name", stored.Name),
+		slog.Any("scopes", stored.Scopes),
+	)
+	writeJSON(w, http.StatusCreated, createKeyResponse{StoredKey: *stored, Key: key})
+}
+
+// This handler revokes a stored API key
+func (r *Router) apiDeleteKeyHandler(w http.ResponseWriter, req *http.Request) {
+	var id = req.PathValue("id")
+	var err = r.auth.DeleteKey(req.Context(), id)
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		writeAPIError(w, http.StatusNotFound, errCodeKeyNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, auth.ErrNoKeyStore) {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error deleting API key", slog.String("error", err.Error()))
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
+		return
+	}
+
+	r.log.InfoContext(req.Context(), "API key revoked", slog.String("key_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
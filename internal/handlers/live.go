@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"home24/internal/analyzer"
+	"home24/internal/jobs"
+)
+
+// This handler starts an analysis from the form as a job and sends the
+// browser to the live progress page
+func (r *Router) liveSubmitHandler(w http.ResponseWriter, req *http.Request) {
+	var err = req.ParseForm()
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error parsing form", slog.String("error", err.Error()))
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var urlString = req.PostForm.Get("url")
+	err = validateTargetURL(urlString)
+	if err != nil {
+		r.renderIndexError(w, req, err.Error(), urlString)
+		return
+	}
+
+	var creds, credsErr = credentialsFromForm(req.PostForm)
+	if credsErr != nil {
+		r.renderIndexError(w, req, credsErr.Error(), urlString)
+		return
+	}
+
+	var job, submitErr = r.submitAnalysisJob(req.Context(), urlString, creds, nil)
+	if submitErr != nil {
+		r.log.ErrorContext(req.Context(), "error submitting job", slog.String("url", urlString), slog.String("error", submitErr.Error()))
+		r.renderIndexError(w, req, "The analyzer is busy, please try again in a moment.", urlString)
+		return
+	}
+
+	http.Redirect(w, req, "/jobs/"+job.ID, http.StatusSeeOther)
+}
+
+// This handler shows the live progress page of a job
+func (r *Router) liveJobHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil {
+		r.notFoundHandler(w, req)
+		return
+	}
+
+	err = r.tmpl.ExecuteTemplate(w, "live.html", map[string]interface{}{
+		"Job": job,
+	})
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// This handler shows the result page of a finished job. It is the fallback
+// for browsers without JavaScript.
+func (r *Router) liveJobResultHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil {
+		r.notFoundHandler(w, req)
+		return
+	}
+
+	switch job.Status {
+	case jobs.StatusSucceeded:
+		var result, _ = job.Result.(*analyzer.AnalysisResult)
+		err = r.tmpl.ExecuteTemplate(w, "result.html", map[string]interface{}{
+			"URL":    job.Target,
+			"Result": result,
+		})
+		if err != nil {
+			r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	case jobs.StatusFailed, jobs.StatusCanceled:
+		r.renderIndexError(w, req, jobFailure(job).Message, job.Target)
+	default:
+		http.Redirect(w, req, "/jobs/"+job.ID, http.StatusSeeOther)
+	}
+}
+
+// This function shows the index page with an error message
+func (r *Router) renderIndexError(w http.ResponseWriter, req *http.Request, message, urlString string) {
+	var templateData = map[string]interface{}{
+		"Error": message,
+		"URL":   urlString,
+	}
+
+	var err = r.tmpl.ExecuteTemplate(w, "index.html", templateData)
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"home24/internal/ratelimit"
+)
+
+// Error codes of rejected requests
+const (
+	errCodeRateLimited   = "RATE_LIMITED"
+	errCodeQuotaExceeded = "QUOTA_EXCEEDED"
+)
+
+// This function wraps a handler so it takes a token from the client's bucket
+// first and answers 429 with a Retry-After header when there is none
+func (r *Router) limited(next http.HandlerFunc) http.HandlerFunc {
+	if r.limiter == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		var decision = r.limiter.Allow(r.limiter.ClientKey(req))
+		if decision.Allowed {
+			next(w, req)
+			return
+		}
+
+		// Round up so clients that wait exactly as long get a token
+		var seconds = int(math.Ceil(decision.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+
+		r.log.WarnContext(req.Context(), "request rejected by rate limiter",
+			slog.String("reason", decision.Reason),
+			slog.String("path", req.URL.Path),
+			slog.Int("retry_after_seconds", seconds),
+		)
+
+		var code, message = errCodeRateLimited, "too many requests, retry later"
+		if decision.Reason == ratelimit.ReasonQuota {
+			code, message = errCodeQuotaExceeded, "daily quota exceeded, retry tomorrow"
+		}
+		if strings.HasPrefix(req.URL.Path, "/api/") {
+			writeAPIError(w, http.StatusTooManyRequests, code, message)
+			return
+		}
+		http.Error(w, message, http.StatusTooManyRequests)
+	}
+}
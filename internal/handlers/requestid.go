@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"home24/pkg/logger"
+)
+
+// This function gives every request an ID, taken from the X-Request-ID
+// header if the caller sent a valid one. The ID is sent back in the same
+// header and every line logged for the request carries it, including the
+// lines of the analyses and jobs it starts.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id = logger.RequestID(r.Header.Get(logger.RequestIDHeader))
+		w.Header().Set(logger.RequestIDHeader, id)
+
+		var ctx = logger.WithAttrs(r.Context(), slog.String(logger.RequestIDKey, id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,14 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"path/filepath"
-	"time"
 
 	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/batch"
+	"home24/internal/jobs"
+	"home24/internal/ratelimit"
+	"home24/internal/sitemap"
+	"home24/internal/store"
+	"home24/internal/webhook"
+	"home24/pkg/logger"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -33,29 +42,71 @@ var templateFuncs = template.FuncMap{
 // This struct contains all the application handlers
 type Router struct {
 	log      *slog.Logger
+	logs     *logger.Logger
 	analyzer analyzer.PageAnalyzer
+	jobs     *jobs.Manager
+	batch    batch.Config
+	sitemap  *sitemap.Checker
+	notifier *webhook.Notifier
+	results  store.ResultStore
+	auth     *auth.Authenticator
+	limiter  *ratelimit.Limiter
+	recorder *store.Recorder
 	tmpl     *template.Template
+	checks   []readinessCheck
+
+	// pageAnalyzer is only set on the admin router, which reports its state
+	pageAnalyzer *analyzer.DefaultPageAnalyzer
 }
 
-// This function creates a new router with all the handlers
-func NewRouter(log *slog.Logger) http.Handler {
+// This function creates a new router with all the handlers. The analyzer
+// is shared with the gRPC server; every analysis it runs is saved to the
+// results store. Jobs run on the job manager, which the router registers
+// its kinds of jobs with, and the notifier sends their webhooks. Batches and
+// sitemaps run with their own settings. Unless the authenticator is nil, the
+// routes require an API key with the scopes they need. The requests that
+// start analyses are limited per client by the limiter unless it is nil. The
+// metrics of the gatherer are served at /metrics unless it is nil. The log
+// level can only be changed on the admin listener, see NewAdminRouter.
+func NewRouter(logs *logger.Logger, pageAnalyzer *analyzer.DefaultPageAnalyzer, results store.ResultStore, jobManager *jobs.Manager, batchConfig batch.Config, sitemapConfig sitemap.Config, notifier *webhook.Notifier, authenticator *auth.Authenticator, limiter *ratelimit.Limiter, gatherer prometheus.Gatherer) http.Handler {
+	var log = logs.Logger
+
 	// Load all the HTML templates with functions
 	var templates = template.Must(template.New("").Funcs(templateFuncs).ParseGlob(filepath.Join("ui", "templates", "*.html")))
 
-	// Create analyzer configuration
-	config := analyzer.DefaultConfig()
-	config.Timeout = 10 * time.Second
-	config.RetryAttempts = 3
-	config.MaxConcurrentLinks = 10
-	config.EnableMetrics = true
+	var recorder = store.NewRecorder(pageAnalyzer, results, pageAnalyzer.Config, log)
 
 	// Create an instance of our router
 	var router = &Router{
 		log:      log,
-		analyzer: analyzer.NewDefaultPageAnalyzer(&config),
+		logs:     logs,
+		analyzer: recorder,
+		jobs:     jobManager,
+		batch:    batchConfig,
+		sitemap:  sitemap.NewChecker(sitemapConfig, pageAnalyzer, recorder, batchConfig.Concurrency),
+		notifier: notifier,
+		results:  results,
+		auth:     authenticator,
+		limiter:  limiter,
+		recorder: recorder,
 		tmpl:     templates,
 	}
 
+	// Register the kinds of jobs, so they can be resumed after a restart
+	router.jobs.Register(jobKindAnalysis, router.buildAnalysisJob)
+	router.jobs.Register(jobKindBatch, router.buildBatchJob)
+	router.jobs.Register(jobKindSitemap, router.buildSitemapJob)
+
+	// Register the dependencies checked for readiness
+	router.checks = []readinessCheck{
+		{name: "templates", check: router.checkTemplates},
+		{name: "config", check: func(ctx context.Context) error { return pageAnalyzer.ConfigError() }},
+		{name: "jobs", check: func(ctx context.Context) error { return router.jobs.Ready() }},
+	}
+	if results != nil {
+		router.checks = append(router.checks, readinessCheck{name: "history", check: results.Ping})
+	}
+
 	// Create a new mux to handle the routes
 	var mux = http.NewServeMux()
 
@@ -64,19 +115,156 @@ func NewRouter(log *slog.Logger) http.Handler {
 		router.indexHandler(w, r)
 	})
 
-	mux.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/analyze", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
 		router.analyzeHandler(w, r)
+	}), auth.ScopeAnalyze))
+
+	// Register the live analysis pages
+	mux.HandleFunc("POST /jobs", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.liveSubmitHandler(w, r)
+	}), auth.ScopeAnalyze))
+
+	mux.HandleFunc("GET /jobs/{id}", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.liveJobHandler(w, r)
+	}, jobReadScopes...))
+
+	mux.HandleFunc("GET /jobs/{id}/result", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.liveJobResultHandler(w, r)
+	}, jobReadScopes...))
+
+	// Register the batch analysis pages
+	mux.HandleFunc("GET /batch", func(w http.ResponseWriter, r *http.Request) {
+		router.batchFormHandler(w, r)
+	})
+
+	mux.HandleFunc("POST /batch", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.batchSubmitHandler(w, r)
+	}), auth.ScopeCrawl))
+
+	mux.HandleFunc("GET /batch/{id}", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.batchReportHandler(w, r)
+	}, jobReadScopes...))
+
+	// Register the sitemap analysis pages
+	mux.HandleFunc("GET /sitemap", func(w http.ResponseWriter, r *http.Request) {
+		router.sitemapFormHandler(w, r)
+	})
+
+	mux.HandleFunc("POST /sitemap", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.sitemapSubmitHandler(w, r)
+	}), auth.ScopeCrawl))
+
+	mux.HandleFunc("GET /sitemap/{id}", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.sitemapReportHandler(w, r)
+	}, jobReadScopes...))
+
+	// Register the history pages
+	mux.HandleFunc("GET /history", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.historyHandler(w, r)
+	}, auth.ScopeReadHistory))
+
+	mux.HandleFunc("GET /history/{id}", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.historyRecordHandler(w, r)
+	}, auth.ScopeReadHistory))
+
+	// Register the comparison pages
+	mux.HandleFunc("GET /compare", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.compareHandler(w, r)
+	}, auth.ScopeReadHistory))
+
+	mux.HandleFunc("POST /compare", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.compareLiveHandler(w, r)
+	}), auth.ScopeReadHistory, auth.ScopeAnalyze))
+
+	// Register the versioned JSON API
+	mux.HandleFunc("POST /api/v1/analyze", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.apiAnalyzeHandler(w, r)
+	}), auth.ScopeAnalyze))
+
+	mux.HandleFunc("POST /api/v1/batch", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.apiSubmitBatchHandler(w, r)
+	}), auth.ScopeCrawl))
+
+	mux.HandleFunc("POST /api/v1/sitemap", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.apiSubmitSitemapHandler(w, r)
+	}), auth.ScopeCrawl))
+
+	mux.HandleFunc("GET /api/v1/history", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.apiHistoryHandler(w, r)
+	}, auth.ScopeReadHistory))
+
+	mux.HandleFunc("GET /api/v1/history/{id}", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+		router.apiHistoryRecordHandler(w, r)
+	}, auth.ScopeReadHistory))
+
+	mux.HandleFunc("POST /api/v1/compare", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.apiCompareHandler(w, r)
+	}), auth.ScopeReadHistory))
+
+	mux.HandleFunc("POST /api/v1/jobs", router.requireScope(router.limited(func(w http.ResponseWriter, r *http.Request) {
+		router.apiSubmitJobHandler(w, r)
+	}), auth.ScopeAnalyze))
+
+	mux.HandleFunc("GET /api/v1/jobs/{id}", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.apiGetJobHandler(w, r)
+	}, jobReadScopes...))
+
+	mux.HandleFunc("GET /api/v1/jobs/{id}/result", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.apiJobResultHandler(w, r)
+	}, jobReadScopes...))
+
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.apiCancelJobHandler(w, r)
+	}, jobReadScopes...))
+
+	mux.HandleFunc("GET /api/v1/jobs/{id}/events", router.requireAnyScope(func(w http.ResponseWriter, r *http.Request) {
+		router.apiJobEventsHandler(w, r)
+	}, jobReadScopes...))
+
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		router.apiNotFoundHandler(w, r)
 	})
 
+	// Register the health, readiness and version endpoints
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		router.healthzHandler(w, r)
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		router.readyzHandler(w, r)
+	})
+
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		router.versionHandler(w, r)
+	})
+
+	// Register the management of API keys
+	if router.auth != nil {
+		mux.HandleFunc("GET /api/v1/keys", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+			router.apiListKeysHandler(w, r)
+		}, auth.ScopeAdmin))
+
+		mux.HandleFunc("POST /api/v1/keys", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+			router.apiCreateKeyHandler(w, r)
+		}, auth.ScopeAdmin))
+
+		mux.HandleFunc("DELETE /api/v1/keys/{id}", router.requireScope(func(w http.ResponseWriter, r *http.Request) {
+			router.apiDeleteKeyHandler(w, r)
+		}, auth.ScopeAdmin))
+	}
+
 	// Add the Prometheus metrics endpoint
-	mux.Handle("/metrics", promhttp.Handler())
+	if gatherer != nil {
+		mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	}
 
 	// Set up the file server for CSS files
 	var cssServer = http.FileServer(http.Dir("ui/css"))
 	mux.Handle("/css/", http.StripPrefix("/css/", cssServer))
 
-	// Return the mux as an http.Handler
-	return mux
+	// Return the mux as an http.Handler that traces every request and
+	// gives it an ID
+	return withRequestID(withTracing(mux))
 }
 
 // This handler shows the main page
@@ -92,7 +280,7 @@ func (r *Router) indexHandler(w http.ResponseWriter, req *http.Request) {
 	var err = r.tmpl.ExecuteTemplate(w, "index.html", nil)
 	if err != nil {
 		// Log the error
-		r.log.Error("error rendering template", slog.String("error", err.Error()))
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
 
 		// Show an error message
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -108,7 +296,7 @@ func (r *Router) notFoundHandler(w http.ResponseWriter, req *http.Request) {
 	var err = r.tmpl.ExecuteTemplate(w, "404.html", nil)
 	if err != nil {
 		// Log the error
-		r.log.Error("error rendering template", slog.String("error", err.Error()))
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
 
 		// Show a simple error message
 		http.Error(w, "Not Found", http.StatusNotFound)
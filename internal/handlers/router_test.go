@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/batch"
+	"home24/internal/jobs"
+	"home24/internal/ratelimit"
+	"home24/internal/sitemap"
+	"home24/internal/store"
+	"home24/internal/webhook"
+	"home24/pkg/logger"
+)
+
+// The templates are loaded relative to the root of the repository
+func TestMain(m *testing.M) {
+	if err := os.Chdir(filepath.Join("..", "..")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// The API keys of the test server
+const (
+	keyAnalyst = "analyst-key"
+	keyOther   = "other-key"
+	keyAdmin   = "admin-key"
+	keyNoScope = "no-scope-key"
+)
+
+// newTestAuthenticator knows two analysts, an admin and a key without scopes
+func newTestAuthenticator() *auth.Authenticator {
+	return auth.NewAuthenticator(auth.Config{Enabled: true, Keys: []auth.KeyConfig{
+		{Name: "analyst", Key: keyAnalyst, Scopes: []string{auth.ScopeAnalyze}},
+		{Name: "other", Key: keyOther, Scopes: []string{auth.ScopeAnalyze}},
+		{Name: "ops", Key: keyAdmin, Scopes: []string{auth.ScopeAdmin}},
+		{Name: "nothing", Key: keyNoScope},
+	}}, nil)
+}
+
+// newTestServer serves the router, requiring API keys if the authenticator
+// isn't nil and limiting requests if the limiter isn't nil. The analyzer may
+// connect to loopback addresses.
+func newTestServer(t *testing.T, authenticator *auth.Authenticator, limiter *ratelimit.Limiter) *httptest.Server {
+	t.Helper()
+
+	logs, err := logger.New(logger.Config{
+		Level:   "debug",
+		Format:  logger.FormatText,
+		Outputs: []string{filepath.Join(t.TempDir(), "test.log")},
+	})
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	t.Cleanup(func() { logs.Close() })
+
+	config := analyzer.DefaultConfig()
+	config.Network.AllowedNetworks = []string{"127.0.0.0/8"}
+	config.RetryAttempts = 1
+
+	jobConfig := jobs.DefaultConfig()
+	jobConfig.CheckpointFile = ""
+	jobManager := jobs.NewManager(jobConfig, logs.Logger)
+
+	server := httptest.NewServer(NewRouter(logs, analyzer.NewDefaultPageAnalyzer(&config, nil), store.NewMemoryStore(), jobManager,
+		batch.DefaultConfig(), sitemap.DefaultConfig(), webhook.NewNotifier(webhook.DefaultConfig(), nil, logs.Logger),
+		authenticator, limiter, nil))
+	t.Cleanup(func() {
+		server.Close()
+		jobManager.Close(context.Background())
+	})
+	return server
+}
+
+// do sends a request with the API key, if any, and returns the response
+func do(t *testing.T, method, url, key, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	if key != "" {
+		req.Header.Set(auth.APIKeyHeader, key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decodeAPIError reads the error body of an API response
+func decodeAPIError(t *testing.T, resp *http.Response) apiError {
+	t.Helper()
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Error decoding error body: %v", err)
+	}
+	return body.Error
+}
+
+// newTestSite serves a page with one working and one broken link
+func newTestSite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	site := httptest.NewServer(mux)
+	t.Cleanup(site.Close)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<!DOCTYPE html><html><head><title>Test</title></head><body>
+			<h1>Test</h1><a href="/ok">ok</a><a href="/missing">missing</a></body></html>`)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	return site
+}
+
+// newHangingSite serves pages that only answer once the test has finished
+func newHangingSite(t *testing.T) *httptest.Server {
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(site.Close)
+	t.Cleanup(func() { close(release) })
+	return site
+}
+
+// submitTestJob submits an analysis of the URL and returns the job
+func submitTestJob(t *testing.T, server *httptest.Server, key, url string) jobs.Job {
+	t.Helper()
+	resp := do(t, http.MethodPost, server.URL+"/api/v1/jobs", key, `{"url":"`+url+`"}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("Error decoding job: %v", err)
+	}
+	return job
+}
+
+// Test analyzer error codes map to HTTP statuses
+func TestStatusForErrorCode(t *testing.T) {
+	tests := map[string]int{
+		analyzer.ErrInvalidURL:         http.StatusBadRequest,
+		analyzer.ErrInvalidCredentials: http.StatusBadRequest,
+		analyzer.ErrBlockedAddress:     http.StatusBadRequest,
+		analyzer.ErrFetchFailed:        http.StatusBadGateway,
+		analyzer.ErrTimeout:            http.StatusGatewayTimeout,
+		analyzer.ErrOverloaded:         http.StatusServiceUnavailable,
+		analyzer.ErrCanceled:           statusClientClosedRequest,
+		analyzer.ErrParseFailed:        http.StatusUnprocessableEntity,
+		analyzer.ErrMaxLinksReached:    http.StatusUnprocessableEntity,
+		analyzer.ErrMaxDepthReached:    http.StatusUnprocessableEntity,
+		"SOMETHING_ELSE":               http.StatusInternalServerError,
+	}
+	for code, status := range tests {
+		if got := statusForErrorCode(code); got != status {
+			t.Errorf("Expected status %d for %s, got %d", status, code, got)
+		}
+	}
+}
+
+// Test the analyze API answers failures with the error code and its status
+func TestAPIAnalyzeErrors(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+
+	tests := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"url":"http://127.0.0.1/","unknown":true}`, http.StatusBadRequest, errCodeInvalidRequest},
+		{`{"url":"ftp://example.com/"}`, http.StatusBadRequest, analyzer.ErrInvalidURL},
+		{`{"url":"http://169.254.169.254/latest/meta-data/"}`, http.StatusBadRequest, analyzer.ErrBlockedAddress},
+		{`{"url":"http://127.0.0.1:1/"}`, http.StatusBadGateway, analyzer.ErrFetchFailed},
+	}
+	for _, test := range tests {
+		resp := do(t, http.MethodPost, server.URL+"/api/v1/analyze", "", test.body)
+		apiErr := decodeAPIError(t, resp)
+		if resp.StatusCode != test.status || apiErr.Code != test.code {
+			t.Errorf("Expected %d %s for %s, got %d %s", test.status, test.code, test.body, resp.StatusCode, apiErr.Code)
+		}
+	}
+
+	resp := do(t, http.MethodGet, server.URL+"/api/v1/unknown", "", "")
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != http.StatusNotFound || apiErr.Code != errCodeNotFound {
+		t.Errorf("Expected 404 %s for an unknown route, got %d %s", errCodeNotFound, resp.StatusCode, apiErr.Code)
+	}
+}
+
+// Test the result of a job canceled while queued is a CANCELED error
+func TestCancelQueuedJobResult(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	site := newHangingSite(t)
+
+	// Keep every worker busy so the last job stays queued
+	var job jobs.Job
+	for i := 0; i <= jobs.DefaultConfig().Workers; i++ {
+		job = submitTestJob(t, server, "", site.URL+"/")
+	}
+
+	resp := do(t, http.MethodDelete, server.URL+"/api/v1/jobs/"+job.ID, "", "")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	resp = do(t, http.MethodGet, server.URL+"/api/v1/jobs/"+job.ID+"/result", "", "")
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != statusClientClosedRequest || apiErr.Code != analyzer.ErrCanceled {
+		t.Errorf("Expected %d %s, got %d %s", statusClientClosedRequest, analyzer.ErrCanceled, resp.StatusCode, apiErr.Code)
+	}
+}
+
+// Test the event stream replays the job's events and ends with its status
+func TestJobEvents(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	site := newTestSite(t)
+	job := submitTestJob(t, server, "", site.URL+"/")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(server.URL + "/api/v1/jobs/" + job.ID + "/events")
+	if err != nil {
+		t.Fatalf("Error opening stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var names []string
+	var data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			data = payload
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Error reading stream: %v", err)
+	}
+
+	if len(names) < 2 || names[0] != string(analyzer.EventFetched) || names[len(names)-1] != "status" {
+		t.Fatalf("Expected the events from fetched to status, got %v", names)
+	}
+	var final jobs.Job
+	if err := json.Unmarshal([]byte(data), &final); err != nil {
+		t.Fatalf("Error decoding status: %v", err)
+	}
+	if final.ID != job.ID || final.Status != jobs.StatusSucceeded {
+		t.Errorf("Expected the job to have succeeded, got %+v", final)
+	}
+}
+
+// Test rejected requests get a 429 with the time to wait
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Config{Enabled: true, RequestsPerMinute: 1, Burst: 1, KeyBy: ratelimit.KeyByIP}, nil)
+	server := newTestServer(t, nil, limiter)
+
+	// The first request takes the only token, even though it is invalid
+	resp := do(t, http.MethodPost, server.URL+"/api/v1/analyze", "", `{}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected the first request through, got %d", resp.StatusCode)
+	}
+
+	resp = do(t, http.MethodPost, server.URL+"/api/v1/analyze", "", `{}`)
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != http.StatusTooManyRequests || apiErr.Code != errCodeRateLimited {
+		t.Fatalf("Expected 429 %s, got %d %s", errCodeRateLimited, resp.StatusCode, apiErr.Code)
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+		t.Errorf("Expected a Retry-After header, got %q", retryAfter)
+	}
+
+	// Routes that don't start analyses aren't limited
+	resp = do(t, http.MethodGet, server.URL+"/api/v1/history", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the history to be served, got %d", resp.StatusCode)
+	}
+}
+
+// Test routes require a valid API key with the scopes they need
+func TestRequireScope(t *testing.T) {
+	server := newTestServer(t, newTestAuthenticator(), nil)
+
+	resp := do(t, http.MethodGet, server.URL+"/api/v1/history", "", "")
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != http.StatusUnauthorized || apiErr.Code != errCodeUnauthorized {
+		t.Errorf("Expected 401 without a key, got %d %s", resp.StatusCode, apiErr.Code)
+	}
+	if resp.Header.Get("WWW-Authenticate") != authRealm {
+		t.Errorf("Expected the key to be asked for, got %q", resp.Header.Get("WWW-Authenticate"))
+	}
+
+	resp = do(t, http.MethodGet, server.URL+"/api/v1/history", "wrong", "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid key, got %d", resp.StatusCode)
+	}
+
+	resp = do(t, http.MethodGet, server.URL+"/api/v1/history", keyAnalyst, "")
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != http.StatusForbidden || apiErr.Code != errCodeForbidden {
+		t.Errorf("Expected 403 without the read-history scope, got %d %s", resp.StatusCode, apiErr.Code)
+	}
+
+	resp = do(t, http.MethodGet, server.URL+"/api/v1/history", keyAdmin, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the admin key to read the history, got %d", resp.StatusCode)
+	}
+
+	// The pages answer in text rather than JSON
+	resp = do(t, http.MethodGet, server.URL+"/history", keyAnalyst, "")
+	if resp.StatusCode != http.StatusForbidden || strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("Expected a 403 page, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+// Test jobs are only visible to the key that submitted them and to admins,
+// and job routes need one of the job scopes
+func TestJobAuthorization(t *testing.T) {
+	server := newTestServer(t, newTestAuthenticator(), nil)
+	site := newTestSite(t)
+	job := submitTestJob(t, server, keyAnalyst, site.URL+"/")
+
+	for key, status := range map[string]int{
+		keyAnalyst: http.StatusOK,
+		keyAdmin:   http.StatusOK,
+		keyOther:   http.StatusNotFound,
+		keyNoScope: http.StatusForbidden,
+	} {
+		resp := do(t, http.MethodGet, server.URL+"/api/v1/jobs/"+job.ID, key, "")
+		if resp.StatusCode != status {
+			t.Errorf("Expected %d for %s, got %d", status, key, resp.StatusCode)
+		}
+	}
+
+	// Other keys can't cancel the job or follow its events either
+	resp := do(t, http.MethodDelete, server.URL+"/api/v1/jobs/"+job.ID, keyOther, "")
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != http.StatusNotFound || apiErr.Code != errCodeJobNotFound {
+		t.Errorf("Expected 404 %s when canceling another key's job, got %d %s", errCodeJobNotFound, resp.StatusCode, apiErr.Code)
+	}
+	resp = do(t, http.MethodGet, server.URL+"/api/v1/jobs/"+job.ID+"/events", keyOther, "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for another key's events, got %d", resp.StatusCode)
+	}
+}
+
+// Test the log level can't be changed on the main port, even without
+// authentication
+func TestLogLevelNotPublic(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		resp := do(t, method, server.URL+"/admin/log-level", "", `{"level":"error"}`)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected %s /admin/log-level to be 404, got %d", method, resp.StatusCode)
+		}
+	}
+}
+
+// Test analyses of different pages can't be compared
+func TestCompareDifferentPages(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	site := newTestSite(t)
+	for _, url := range []string{site.URL + "/", site.URL + "/ok"} {
+		resp := do(t, http.MethodPost, server.URL+"/api/v1/analyze", "", `{"url":"`+url+`"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %s to be analyzed, got %d", url, resp.StatusCode)
+		}
+	}
+
+	resp := do(t, http.MethodGet, server.URL+"/api/v1/history", "", "")
+	var history struct {
+		Items []store.Summary `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil || len(history.Items) != 2 {
+		t.Fatalf("Expected two analyses in the history, got %v %v", history.Items, err)
+	}
+	before, after := history.Items[0].ID, history.Items[1].ID
+
+	resp = do(t, http.MethodPost, server.URL+"/api/v1/compare", "", `{"before":"`+before+`","after":"`+after+`"}`)
+	if apiErr := decodeAPIError(t, resp); resp.StatusCode != http.StatusBadRequest || apiErr.Code != errCodeInvalidRequest {
+		t.Errorf("Expected 400 %s, got %d %s", errCodeInvalidRequest, resp.StatusCode, apiErr.Code)
+	}
+
+	resp = do(t, http.MethodGet, server.URL+"/compare?before="+before+"&after="+after, "", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected the comparison page to answer 400, got %d", resp.StatusCode)
+	}
+}
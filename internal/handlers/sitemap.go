@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"home24/internal/analyzer"
+	"home24/internal/jobs"
+	"home24/internal/sitemap"
+	"home24/internal/webhook"
+)
+
+// The job kind for sitemap analyses
+const jobKindSitemap = "sitemap"
+
+// This handler shows the sitemap analysis form
+func (r *Router) sitemapFormHandler(w http.ResponseWriter, req *http.Request) {
+	r.renderSitemapForm(w, req, "", "")
+}
+
+// This handler starts a sitemap analysis from the form
+func (r *Router) sitemapSubmitHandler(w http.ResponseWriter, req *http.Request) {
+	var err = req.ParseForm()
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error parsing form", slog.String("error", err.Error()))
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var urlString = req.PostForm.Get("url")
+	err = validateTargetURL(urlString)
+	if err != nil {
+		r.renderSitemapForm(w, req, err.Error(), urlString)
+		return
+	}
+
+	var creds, credsErr = credentialsFromForm(req.PostForm)
+	if credsErr != nil {
+		r.renderSitemapForm(w, req, credsErr.Error(), urlString)
+		return
+	}
+
+	var job, submitErr = r.submitSitemapJob(req.Context(), urlString, creds, nil)
+	if submitErr != nil {
+		r.log.ErrorContext(req.Context(), "error submitting sitemap job", slog.String("url", urlString), slog.String("error", submitErr.Error()))
+		r.renderSitemapForm(w, req, "The analyzer is busy, please try again in a moment.", urlString)
+		return
+	}
+
+	http.Redirect(w, req, "/sitemap/"+job.ID, http.StatusSeeOther)
+}
+
+// This handler shows the progress of a sitemap job and its report once it has finished
+func (r *Router) sitemapReportHandler(w http.ResponseWriter, req *http.Request) {
+	var job, err = r.callerJob(req)
+	if err != nil || job.Kind != jobKindSitemap {
+		r.notFoundHandler(w, req)
+		return
+	}
+
+	var report, _ = job.Result.(*sitemap.Report)
+	err = r.tmpl.ExecuteTemplate(w, "sitemap_report.html", map[string]interface{}{
+		"Job":    job,
+		"Report": report,
+	})
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// This handler starts a sitemap analysis through the API
+func (r *Router) apiSubmitSitemapHandler(w http.ResponseWriter, req *http.Request) {
+	var body jobRequest
+	var err = decodeJSON(w, req, &body)
+	if err == nil {
+		err = r.validateWebhooks(req.Context(), body.Webhooks)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, requestErrorCode(err), err.Error())
+		return
+	}
+
+	err = validateTargetURL(body.URL)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, analyzer.ErrInvalidURL, err.Error())
+		return
+	}
+
+	var job, submitErr = r.submitSitemapJob(req.Context(), body.URL, body.Credentials, body.Webhooks)
+	if submitErr != nil {
+		writeJobError(w, submitErr)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// This function queues a sitemap analysis as a job
+func (r *Router) submitSitemapJob(ctx context.Context, urlString string, creds *analyzer.Credentials, webhooks []webhook.Endpoint) (jobs.Job, error) {
+	return r.submitJob(ctx, jobKindSitemap, urlString, jobParams{URL: urlString, Credentials: creds, Webhooks: webhooks})
+}
+
+// This function builds the task of a sitemap job from its parameters
+func (r *Router) buildSitemapJob(raw json.RawMessage) (jobs.Task, []jobs.FinishHook, error) {
+	var params jobParams
+	var err = json.Unmarshal(raw, &params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var task = func(ctx context.Context) (interface{}, error) {
+		if params.Credentials != nil {
+			ctx = analyzer.WithCredentials(ctx, *params.Credentials)
+		}
+		return r.sitemap.Run(ctx, params.URL)
+	}
+	return task, r.finishHooks(params.Webhooks), nil
+}
+
+// This function shows the sitemap form with an optional error message
+func (r *Router) renderSitemapForm(w http.ResponseWriter, req *http.Request, message, urlString string) {
+	var err = r.tmpl.ExecuteTemplate(w, "sitemap.html", map[string]interface{}{
+		"Error": message,
+		"URL":   urlString,
+	})
+	if err != nil {
+		r.log.ErrorContext(req.Context(), "error rendering template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the tracer the HTTP server creates its spans with
+const tracerName = "home24/internal/handlers"
+
+// This function wraps the mux so every request gets a server span. The trace
+// context of the caller is taken from the traceparent header, so analyses
+// show up in the caller's trace.
+func withTracing(mux *http.ServeMux) http.Handler {
+	var tracer = otel.Tracer(tracerName)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx = otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		// Name the span after the route, not the path, to keep names few
+		_, pattern := mux.Handler(r)
+		var name = r.Method
+		if pattern != "" {
+			name = pattern
+		}
+
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.HTTPRoute(pattern),
+			),
+		)
+		defer span.End()
+
+		var recorder = &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+		if recorder.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// This struct remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the original writer, e.g. to
+// flush server-sent events
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"home24/internal/auth"
+	"home24/pkg/logger"
+)
+
+// saved is a job in the checkpoint file. Jobs whose parameters hold secrets
+// are submitted with SubmitUnsaved and never saved; the file is still only
+// readable by its owner.
+type saved struct {
+	ID        string            `json:"id"`
+	Kind      string            `json:"kind"`
+	Target    string            `json:"target"`
+	Params    json.RawMessage   `json:"params"`
+	CreatedAt time.Time         `json:"created_at"`
+	Identity  *auth.Identity    `json:"identity,omitempty"`
+	LogAttrs  map[string]string `json:"log_attrs,omitempty"`
+}
+
+// requeue puts a job that was interrupted back into the queued state
+func (j *job) requeue() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.Status = StatusQueued
+	j.info.StartedAt = nil
+	j.info.Progress = Progress{}
+	j.cancel = nil
+	j.events = nil
+}
+
+// checkpoint writes the queued resumable jobs to the checkpoint file, in
+// the order they were submitted. Without such jobs the file is removed.
+func (m *Manager) checkpoint() error {
+	if m.config.CheckpointFile == "" {
+		return nil
+	}
+
+	var jobs []saved
+	m.mu.RLock()
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		if j.params != nil && j.info.Status == StatusQueued {
+			attrs := make(map[string]string)
+			for _, attr := range j.logAttrs {
+				attrs[attr.Key] = attr.Value.String()
+			}
+			jobs = append(jobs, saved{
+				ID:        j.info.ID,
+				Kind:      j.info.Kind,
+				Target:    j.info.Target,
+				Params:    j.params,
+				CreatedAt: j.info.CreatedAt,
+				Identity:  j.info.Owner,
+				LogAttrs:  attrs,
+			})
+		}
+		j.mu.Unlock()
+	}
+	m.mu.RUnlock()
+
+	if len(jobs) == 0 {
+		err := os.Remove(m.config.CheckpointFile)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].CreatedAt.Before(jobs[b].CreatedAt) })
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.config.CheckpointFile), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash doesn't leave half a file
+	tmp := m.config.CheckpointFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, m.config.CheckpointFile); err != nil {
+		return err
+	}
+	m.log.Info("saved jobs to resume on the next start", slog.String("path", m.config.CheckpointFile), slog.Int("jobs", len(jobs)))
+	return nil
+}
+
+// Resume queues the jobs saved at the last shutdown again, with their IDs,
+// and removes the checkpoint file. It waits for room in the queue, so call
+// it before serving requests. Jobs of a kind without a builder can't be
+// resumed and are dropped with an error in the log. Unless the
+// authenticator is nil, the keys the jobs were submitted with are checked
+// again and the jobs of keys that were deleted since are dropped too. It
+// returns the number of resumed jobs.
+func (m *Manager) Resume(ctx context.Context, authenticator *auth.Authenticator) (int, error) {
+	if m.config.CheckpointFile == "" {
+		return 0, nil
+	}
+	data, err := os.ReadFile(m.config.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var jobs []saved
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return 0, fmt.Errorf("error reading job checkpoint %s: %w", m.config.CheckpointFile, err)
+	}
+
+	resumed := 0
+	for _, s := range jobs {
+		var attrs []slog.Attr
+		for key, value := range s.LogAttrs {
+			attrs = append(attrs, slog.String(key, value))
+		}
+		jobCtx := logger.WithAttrs(ctx, attrs...)
+		jobCtx = logger.WithAttrs(jobCtx, slog.String("job_id", s.ID))
+
+		m.mu.RLock()
+		build, ok := m.builders[s.Kind]
+		m.mu.RUnlock()
+		if !ok {
+			m.log.ErrorContext(jobCtx, "cannot resume job of unknown kind", slog.String("kind", s.Kind))
+			continue
+		}
+		task, hooks, err := build(s.Params)
+		if err != nil {
+			m.log.ErrorContext(jobCtx, "cannot resume job", slog.String("error", err.Error()))
+			continue
+		}
+		if authenticator != nil && s.Identity != nil {
+			s.Identity, err = authenticator.Recheck(ctx, s.Identity)
+			if err != nil {
+				m.log.WarnContext(jobCtx, "cannot resume job, its API key is no longer valid", slog.String("error", err.Error()))
+				continue
+			}
+		}
+
+		j := &job{
+			info: Job{
+				ID:        s.ID,
+				Kind:      s.Kind,
+				Target:    s.Target,
+				Status:    StatusQueued,
+				CreatedAt: s.CreatedAt,
+				Owner:     s.Identity,
+			},
+			task:     task,
+			hooks:    hooks,
+			hookWG:   &m.hooks,
+			hookCtx:  m.hooksCtx,
+			logAttrs: attrs,
+			params:   s.Params,
+		}
+		if err := m.requeueSaved(ctx, j); err != nil {
+			return resumed, err
+		}
+		resumed++
+		m.log.InfoContext(jobCtx, "job resumed", slog.String("kind", s.Kind), slog.String("target", s.Target))
+	}
+
+	if err := os.Remove(m.config.CheckpointFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return resumed, err
+	}
+	return resumed, nil
+}
+
+// requeueSaved adds a resumed job to the queue, waiting for room as the
+// queue may be smaller than the number of saved jobs
+func (m *Manager) requeueSaved(ctx context.Context, j *job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	select {
+	case m.queue <- j:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	m.jobs[j.info.ID] = j
+	return nil
+}
@@ -0,0 +1,654 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/pkg/logger"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+// Job states. Succeeded, failed and canceled are final.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Finished reports whether the status is final
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Errors returned by the Manager
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrNotFound    = errors.New("job not found")
+	ErrFinished    = errors.New("job already finished")
+	ErrNotFinished = errors.New("job not finished")
+	ErrClosed      = errors.New("job manager is closed")
+)
+
+// Task is the work a job performs. Its context is canceled when the job is
+// canceled and reports analysis events to the job.
+type Task func(ctx context.Context) (interface{}, error)
+
+// Builder creates the task and finish hooks of a resumable job from the
+// parameters it was submitted with, both on submission and when the job is
+// resumed after a restart
+type Builder func(params json.RawMessage) (Task, []FinishHook, error)
+
+// Progress describes how far a running job has got
+type Progress struct {
+	Stage        analyzer.EventType `json:"stage,omitempty"`
+	LinksChecked int                `json:"links_checked"`
+	LinksTotal   int                `json:"links_total"`
+	PagesDone    int                `json:"pages_done,omitempty"`
+	PagesTotal   int                `json:"pages_total,omitempty"`
+}
+
+// Error describes why a job failed
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Job is a snapshot of a submitted job
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Target     string      `json:"target"`
+	Status     Status      `json:"status"`
+	Progress   Progress    `json:"progress"`
+	Error      *Error      `json:"error,omitempty"`
+	Result     interface{} `json:"-"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+
+	// Owner is the API key of the submitter, which the analyses of the job
+	// are attributed to; nil without authentication
+	Owner *auth.Identity `json:"-"`
+}
+
+// FinishHook is called with the final snapshot once a job has finished,
+// whether it succeeded, failed or was canceled. It runs in its own goroutine.
+// Its context is canceled when the manager stops waiting for the hooks on
+// shutdown, so it should give up on whatever it still has to do then.
+type FinishHook func(ctx context.Context, job Job)
+
+// Config holds the job subsystem settings
+type Config struct {
+	Workers   int           `yaml:"workers"`
+	QueueSize int           `yaml:"queueSize"`
+	Retention time.Duration `yaml:"retention"`
+
+	// CheckpointFile is where resumable jobs that are queued or running at
+	// shutdown are saved, to be resumed on the next start. Empty disables
+	// checkpoints.
+	CheckpointFile string `yaml:"checkpointFile"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Workers:        4,
+		QueueSize:      100,
+		Retention:      time.Hour,
+		CheckpointFile: "data/jobs.json",
+	}
+}
+
+// The most analysis events kept per job for late subscribers
+const maxEventHistory = 1000
+
+// The number of events buffered per subscriber before events are dropped
+const subscriberBuffer = 64
+
+// job is the mutable state behind a Job snapshot
+type job struct {
+	mu      sync.Mutex
+	info    Job
+	task    Task
+	hooks   []FinishHook
+	hookWG  *sync.WaitGroup
+	hookCtx context.Context
+	cancel  context.CancelFunc
+
+	// logAttrs are the log attributes of the submitter, e.g. its request ID
+	logAttrs []slog.Attr
+
+	// params are the parameters of a resumable job; nil for other jobs
+	params json.RawMessage
+
+	events      []analyzer.Event
+	subscribers map[chan analyzer.Event]struct{}
+}
+
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+// handleEvent updates the job progress from an analysis event
+func (j *job) handleEvent(event analyzer.Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.info.Progress.Stage = event.Type
+	switch event.Type {
+	case analyzer.EventParsed:
+		j.info.Progress.LinksTotal = event.LinksTotal
+	case analyzer.EventLinkChecked, analyzer.EventDone:
+		j.info.Progress.LinksChecked = event.LinksChecked
+		j.info.Progress.LinksTotal = event.LinksTotal
+	}
+
+	if len(j.events) < maxEventHistory {
+		j.events = append(j.events, event)
+	}
+	for ch := range j.subscribers {
+		// Slow subscribers miss events rather than stall the analysis
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// finish records the final state and closes the subscriber channels
+func (j *job) finish(status Status, result interface{}, err *Error) Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finishLocked(status, result, err)
+}
+
+// finishLocked is finish for callers that already hold the job lock
+func (j *job) finishLocked(status Status, result interface{}, err *Error) Job {
+	now := time.Now()
+	j.info.Status = status
+	j.info.Result = result
+	j.info.Error = err
+	j.info.FinishedAt = &now
+	j.cancel = nil
+
+	for ch := range j.subscribers {
+		close(ch)
+	}
+	j.subscribers = nil
+	for _, hook := range j.hooks {
+		j.hookWG.Add(1)
+		go func(hook FinishHook, info Job) {
+			defer j.hookWG.Done()
+			hook(j.hookCtx, info)
+		}(hook, j.info)
+	}
+	return j.info
+}
+
+type jobKey struct{}
+
+// IDFromContext returns the ID of the job running with the context
+func IDFromContext(ctx context.Context) (string, bool) {
+	j, ok := ctx.Value(jobKey{}).(*job)
+	if !ok {
+		return "", false
+	}
+	return j.info.ID, true
+}
+
+// ReportPages records the page progress of a job that analyzes several
+// pages. It does nothing outside of a job.
+func ReportPages(ctx context.Context, done, total int) {
+	j, ok := ctx.Value(jobKey{}).(*job)
+	if !ok {
+		return
+	}
+	j.mu.Lock()
+	j.info.Progress.PagesDone = done
+	j.info.Progress.PagesTotal = total
+	j.mu.Unlock()
+}
+
+// Manager runs submitted jobs on a bounded pool of workers
+type Manager struct {
+	config Config
+	log    *slog.Logger
+
+	mu       sync.RWMutex
+	jobs     map[string]*job
+	queue    chan *job
+	closed   bool
+	builders map[string]Builder
+
+	// draining is set once Close was called; workers then leave resumable
+	// jobs in the queue to be saved
+	draining atomic.Bool
+
+	ctx    context.Context
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	ticker *time.Ticker
+
+	// hooks tracks the running finish hooks, whose context is canceled by
+	// stopHooks
+	hooks     sync.WaitGroup
+	hooksCtx  context.Context
+	stopHooks context.CancelFunc
+}
+
+// NewManager creates a Manager and starts its workers
+func NewManager(config Config, log *slog.Logger) *Manager {
+	defaults := DefaultConfig()
+	if config.Workers < 1 {
+		config.Workers = defaults.Workers
+	}
+	if config.QueueSize < 1 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	hooksCtx, stopHooks := context.WithCancel(context.Background())
+	m := &Manager{
+		config:    config,
+		log:       log,
+		jobs:      make(map[string]*job),
+		queue:     make(chan *job, config.QueueSize),
+		builders:  make(map[string]Builder),
+		ctx:       ctx,
+		stop:      stop,
+		ticker:    time.NewTicker(config.Retention / 2),
+		hooksCtx:  hooksCtx,
+		stopHooks: stopHooks,
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	go m.expire()
+
+	return m
+}
+
+// Register sets the builder of a kind of resumable jobs. It must be called
+// before jobs of that kind are submitted or resumed.
+func (m *Manager) Register(kind string, build Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.builders[kind] = build
+}
+
+// Submit queues a task and returns the new job straight away. The hooks are
+// called once the job has finished. The job doesn't end with ctx, it only
+// takes over its log attributes. Jobs submitted this way are lost on
+// shutdown; see SubmitResumable.
+func (m *Manager) Submit(ctx context.Context, kind, target string, task Task, hooks ...FinishHook) (Job, error) {
+	j, err := m.newJob(ctx, kind, target, task, hooks)
+	if err != nil {
+		return Job{}, err
+	}
+	return m.enqueue(ctx, j)
+}
+
+// SubmitResumable queues a job whose task is built from the parameters by
+// the builder registered for its kind. The parameters are encoded as JSON
+// and saved at shutdown if the job hasn't finished, so it can be resumed.
+func (m *Manager) SubmitResumable(ctx context.Context, kind, target string, params interface{}) (Job, error) {
+	return m.submitBuilt(ctx, kind, target, params, true)
+}
+
+// SubmitUnsaved queues a job built like the ones of SubmitResumable whose
+// parameters are never saved, for parameters holding secrets. It is lost on
+// shutdown like the jobs of Submit.
+func (m *Manager) SubmitUnsaved(ctx context.Context, kind, target string, params interface{}) (Job, error) {
+	return m.submitBuilt(ctx, kind, target, params, false)
+}
+
+// submitBuilt queues a job built from its parameters, keeping them to save
+// at shutdown if it is resumable
+func (m *Manager) submitBuilt(ctx context.Context, kind, target string, params interface{}, resumable bool) (Job, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return Job{}, fmt.Errorf("error encoding job parameters: %w", err)
+	}
+	m.mu.RLock()
+	build, ok := m.builders[kind]
+	m.mu.RUnlock()
+	if !ok {
+		return Job{}, fmt.Errorf("no builder registered for %q jobs", kind)
+	}
+	task, hooks, err := build(raw)
+	if err != nil {
+		return Job{}, err
+	}
+
+	j, err := m.newJob(ctx, kind, target, task, hooks)
+	if err != nil {
+		return Job{}, err
+	}
+	if resumable {
+		j.params = raw
+	}
+	return m.enqueue(ctx, j)
+}
+
+// newJob creates a queued job with a new ID
+func (m *Manager) newJob(ctx context.Context, kind, target string, task Task, hooks []FinishHook) (*job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &job{
+		info: Job{
+			ID:        id,
+			Kind:      kind,
+			Target:    target,
+			Status:    StatusQueued,
+			CreatedAt: time.Now(),
+			Owner:     auth.FromContext(ctx),
+		},
+		task:     task,
+		hooks:    hooks,
+		hookWG:   &m.hooks,
+		hookCtx:  m.hooksCtx,
+		logAttrs: logger.Attrs(ctx),
+	}, nil
+}
+
+// enqueue adds a job to the queue unless it is full
+func (m *Manager) enqueue(ctx context.Context, j *job) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Job{}, ErrClosed
+	}
+
+	select {
+	case m.queue <- j:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[j.info.ID] = j
+
+	m.log.InfoContext(ctx, "job queued", slog.String("job_id", j.info.ID), slog.String("kind", j.info.Kind), slog.String("target", j.info.Target))
+	return j.snapshot(), nil
+}
+
+// Get returns a snapshot of the job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.RLock()
+	j, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// Cancel stops a queued or running job. Running jobs are stopped through
+// the context passed to their task.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.RLock()
+	j, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+
+	j.mu.Lock()
+	switch {
+	case j.info.Status.Finished():
+		j.mu.Unlock()
+		return j.snapshot(), ErrFinished
+	case j.info.Status == StatusQueued:
+		// The worker skips jobs that were canceled while queued
+		j.finishLocked(StatusCanceled, nil, &Error{Code: analyzer.ErrCanceled, Message: "job canceled before it started"})
+	case j.cancel != nil:
+		j.cancel()
+	}
+	j.mu.Unlock()
+
+	m.log.Info("job cancel requested", slog.String("job_id", id))
+	return j.snapshot(), nil
+}
+
+// Subscribe returns the events the job has reported so far and a channel
+// with the ones that follow. The channel is closed when the job finishes;
+// call unsubscribe to stop listening earlier.
+func (m *Manager) Subscribe(id string) (history []analyzer.Event, events <-chan analyzer.Event, unsubscribe func(), err error) {
+	m.mu.RLock()
+	j, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil, nil, ErrNotFound
+	}
+
+	ch := make(chan analyzer.Event, subscriberBuffer)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	history = append([]analyzer.Event{}, j.events...)
+	if j.info.Status.Finished() {
+		close(ch)
+		return history, ch, func() {}, nil
+	}
+	if j.subscribers == nil {
+		j.subscribers = make(map[chan analyzer.Event]struct{})
+	}
+	j.subscribers[ch] = struct{}{}
+
+	unsubscribe = func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if _, ok := j.subscribers[ch]; ok {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+	return history, ch, unsubscribe, nil
+}
+
+// Ready reports whether the manager accepts new jobs
+func (m *Manager) Ready() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+	if len(m.queue) == cap(m.queue) {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// Close stops accepting jobs and lets the running ones finish until the
+// context is done, then cancels them. Resumable jobs that are still queued
+// or were interrupted don't finish but are saved to the checkpoint file, so
+// Resume can run them again; other queued jobs are canceled. Close returns
+// once the workers have exited and the finish hooks have run. If the hooks
+// are still running when the context is done, their context is canceled and
+// Close returns the error of the context once they have given up.
+func (m *Manager) Close(ctx context.Context) error {
+	m.draining.Store(true)
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	m.ticker.Stop()
+
+	workersDone := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		m.log.Warn("canceling running jobs", slog.String("reason", ctx.Err().Error()))
+	}
+	m.stop()
+	<-workersDone
+
+	err := m.checkpoint()
+	if err != nil {
+		m.log.Error("error saving job checkpoint", slog.String("path", m.config.CheckpointFile), slog.String("error", err.Error()))
+	}
+
+	hooksDone := make(chan struct{})
+	go func() {
+		m.hooks.Wait()
+		close(hooksDone)
+	}()
+	select {
+	case <-hooksDone:
+		return err
+	case <-ctx.Done():
+		m.log.Warn("canceling finish hooks", slog.String("reason", ctx.Err().Error()))
+		m.stopHooks()
+		<-hooksDone
+		return errors.Join(err, ctx.Err())
+	}
+}
+
+// worker runs queued jobs until the queue is closed
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for j := range m.queue {
+		m.run(j)
+	}
+}
+
+// run executes a single job
+func (m *Manager) run(j *job) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	j.mu.Lock()
+	if j.info.Status != StatusQueued {
+		j.mu.Unlock()
+		return
+	}
+	if m.draining.Load() {
+		// Resumable jobs stay queued to be saved, the others can't be run
+		// again and are canceled
+		if j.params == nil {
+			j.finishLocked(StatusCanceled, nil, &Error{Code: analyzer.ErrCanceled, Message: "job canceled by shutdown"})
+		}
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	j.info.Status = StatusRunning
+	j.info.StartedAt = &now
+	j.cancel = cancel
+	j.mu.Unlock()
+
+	// Everything logged for the job carries its ID and the submitter's
+	// attributes, and its analyses are attributed to the submitter's key
+	ctx = logger.WithAttrs(ctx, j.logAttrs...)
+	ctx = logger.WithAttrs(ctx, slog.String("job_id", j.info.ID))
+	if j.info.Owner != nil {
+		ctx = auth.WithIdentity(ctx, j.info.Owner)
+	}
+	m.log.InfoContext(ctx, "job started")
+
+	ctx = context.WithValue(ctx, jobKey{}, j)
+	ctx = analyzer.WithEventHandler(ctx, j.handleEvent)
+	result, err := j.task(ctx)
+
+	// A resumable job interrupted by shutdown is queued again to be saved
+	// instead of finishing as canceled
+	if err != nil && m.ctx.Err() != nil && j.params != nil {
+		j.requeue()
+		m.log.InfoContext(ctx, "job interrupted by shutdown")
+		return
+	}
+
+	var info Job
+	switch {
+	case err == nil:
+		info = j.finish(StatusSucceeded, result, nil)
+	case ctx.Err() != nil:
+		info = j.finish(StatusCanceled, nil, jobError(err))
+	default:
+		info = j.finish(StatusFailed, nil, jobError(err))
+	}
+
+	var attrs = []slog.Attr{
+		slog.String("status", string(info.Status)),
+		slog.Duration("duration", info.FinishedAt.Sub(now)),
+	}
+	var level = slog.LevelInfo
+	if info.Error != nil {
+		attrs = append(attrs, slog.String("error_code", info.Error.Code), slog.String("error", info.Error.Message))
+	}
+	if info.Status == StatusFailed {
+		level = slog.LevelError
+	}
+	m.log.LogAttrs(ctx, level, "job finished", attrs...)
+}
+
+// expire removes finished jobs once they are older than the retention
+func (m *Manager) expire() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.ticker.C:
+		}
+
+		cutoff := time.Now().Add(-m.config.Retention)
+		m.mu.Lock()
+		for id, j := range m.jobs {
+			info := j.snapshot()
+			if info.FinishedAt != nil && info.FinishedAt.Before(cutoff) {
+				delete(m.jobs, id)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// jobError converts a task error into a job error
+func jobError(err error) *Error {
+	var analysisErr *analyzer.AnalysisError
+	if errors.As(err, &analysisErr) {
+		message := analysisErr.Message
+		if analysisErr.Err != nil {
+			message += ": " + analysisErr.Err.Error()
+		}
+		return &Error{Code: analysisErr.Code, Message: message}
+	}
+	return &Error{Code: "JOB_FAILED", Message: err.Error()}
+}
+
+// newID returns a random job ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
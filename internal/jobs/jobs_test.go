@@ -0,0 +1,407 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+)
+
+func newTestManager(config Config) *Manager {
+	return NewManager(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// waitForStatus polls the job until it reaches a final state
+func waitForStatus(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Error getting job: %v", err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish in time", id)
+	return Job{}
+}
+
+// Test a job runs and records its result
+func TestSubmitAndComplete(t *testing.T) {
+	m := newTestManager(DefaultConfig())
+	defer m.Close(context.Background())
+
+	job, err := m.Submit(context.Background(), "analysis", "https://example.com", func(ctx context.Context) (interface{}, error) {
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatalf("Error submitting job: %v", err)
+	}
+	if job.Status != StatusQueued {
+		t.Errorf("Expected queued status, got %s", job.Status)
+	}
+
+	job = waitForStatus(t, m, job.ID)
+	if job.Status != StatusSucceeded {
+		t.Errorf("Expected succeeded status, got %s", job.Status)
+	}
+	if job.Result != "done" {
+		t.Errorf("Expected result 'done', got %v", job.Result)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Error("Expected start and finish times to be set")
+	}
+}
+
+// Test canceling a running job cancels the task context
+func TestCancelRunningJob(t *testing.T) {
+	m := newTestManager(DefaultConfig())
+	defer m.Close(context.Background())
+
+	started := make(chan struct{})
+	job, err := m.Submit(context.Background(), "analysis", "https://example.com", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, analyzer.NewAnalysisError(analyzer.ErrCanceled, "analysis stopped", ctx.Err())
+	})
+	if err != nil {
+		t.Fatalf("Error submitting job: %v", err)
+	}
+
+	<-started
+	if _, err := m.Cancel(job.ID); err != nil {
+		t.Fatalf("Error canceling job: %v", err)
+	}
+
+	job = waitForStatus(t, m, job.ID)
+	if job.Status != StatusCanceled {
+		t.Errorf("Expected canceled status, got %s", job.Status)
+	}
+	if job.Error == nil || job.Error.Code != analyzer.ErrCanceled {
+		t.Errorf("Expected %s error, got %+v", analyzer.ErrCanceled, job.Error)
+	}
+
+	if _, err := m.Cancel(job.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Expected ErrFinished when canceling twice, got %v", err)
+	}
+}
+
+// Test the queue is bounded and queued jobs can be canceled
+func TestQueueFull(t *testing.T) {
+	m := newTestManager(Config{Workers: 1, QueueSize: 1})
+	defer m.Close(context.Background())
+
+	block := make(chan struct{})
+	defer close(block)
+	running := make(chan struct{})
+	blocking := func(ctx context.Context) (interface{}, error) {
+		running <- struct{}{}
+		<-block
+		return nil, nil
+	}
+
+	if _, err := m.Submit(context.Background(), "analysis", "a", blocking); err != nil {
+		t.Fatalf("Error submitting first job: %v", err)
+	}
+	<-running
+
+	queued, err := m.Submit(context.Background(), "analysis", "b", blocking)
+	if err != nil {
+		t.Fatalf("Error submitting second job: %v", err)
+	}
+
+	if _, err := m.Submit(context.Background(), "analysis", "c", blocking); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+	if err := m.Ready(); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected a full queue not to be ready, got %v", err)
+	}
+
+	canceled, err := m.Cancel(queued.ID)
+	if err != nil {
+		t.Fatalf("Error canceling queued job: %v", err)
+	}
+	if canceled.Status != StatusCanceled {
+		t.Errorf("Expected canceled status, got %s", canceled.Status)
+	}
+	if canceled.Error == nil || canceled.Error.Code != analyzer.ErrCanceled {
+		t.Errorf("Expected %s error, got %+v", analyzer.ErrCanceled, canceled.Error)
+	}
+}
+
+// Test a manager is ready until it is closed
+func TestReady(t *testing.T) {
+	m := newTestManager(Config{})
+	if err := m.Ready(); err != nil {
+		t.Errorf("Expected a new manager to be ready, got %v", err)
+	}
+
+	m.Close(context.Background())
+	if err := m.Ready(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// Test progress is tracked from analysis events
+func TestProgressFromEvents(t *testing.T) {
+	j := &job{info: Job{Status: StatusRunning}}
+	j.handleEvent(analyzer.Event{Type: analyzer.EventParsed, LinksTotal: 3})
+	j.handleEvent(analyzer.Event{Type: analyzer.EventLinkChecked, LinksChecked: 1, LinksTotal: 3})
+
+	progress := j.snapshot().Progress
+	if progress.Stage != analyzer.EventLinkChecked {
+		t.Errorf("Expected stage %s, got %s", analyzer.EventLinkChecked, progress.Stage)
+	}
+	if progress.LinksChecked != 1 || progress.LinksTotal != 3 {
+		t.Errorf("Expected 1 of 3 links checked, got %d of %d", progress.LinksChecked, progress.LinksTotal)
+	}
+}
+
+// Test subscribers get the event history, live events and a closed channel
+func TestSubscribe(t *testing.T) {
+	m := newTestManager(DefaultConfig())
+	defer m.Close(context.Background())
+
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+	job, err := m.Submit(context.Background(), "analysis", "https://example.com", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-proceed
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Error submitting job: %v", err)
+	}
+	<-started
+
+	m.mu.RLock()
+	j := m.jobs[job.ID]
+	m.mu.RUnlock()
+	j.handleEvent(analyzer.Event{Type: analyzer.EventFetched})
+
+	history, events, unsubscribe, err := m.Subscribe(job.ID)
+	if err != nil {
+		t.Fatalf("Error subscribing: %v", err)
+	}
+	defer unsubscribe()
+	if len(history) != 1 || history[0].Type != analyzer.EventFetched {
+		t.Errorf("Expected the fetched event in the history, got %v", history)
+	}
+
+	j.handleEvent(analyzer.Event{Type: analyzer.EventParsed, LinksTotal: 2})
+	if event := <-events; event.Type != analyzer.EventParsed {
+		t.Errorf("Expected a parsed event, got %s", event.Type)
+	}
+
+	close(proceed)
+	waitForStatus(t, m, job.ID)
+	if _, ok := <-events; ok {
+		t.Error("Expected the channel to be closed once the job finished")
+	}
+
+	if _, _, _, err := m.Subscribe("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+// Test finish hooks are called with the final job, also for canceled ones
+func TestFinishHooks(t *testing.T) {
+	m := newTestManager(Config{Workers: 1, QueueSize: 10, Retention: time.Hour})
+
+	finished := make(chan Job, 2)
+	hook := func(ctx context.Context, job Job) { finished <- job }
+
+	release := make(chan struct{})
+	running, _ := m.Submit(context.Background(), "analysis", "https://example.com/a", func(ctx context.Context) (interface{}, error) {
+		<-release
+		return "done", nil
+	}, hook)
+	queued, _ := m.Submit(context.Background(), "analysis", "https://example.com/b", func(ctx context.Context) (interface{}, error) {
+		return "done", nil
+	}, hook)
+
+	m.Cancel(queued.ID)
+	job := <-finished
+	if job.ID != queued.ID || job.Status != StatusCanceled {
+		t.Errorf("Expected the canceled job first, got %s with status %s", job.ID, job.Status)
+	}
+
+	close(release)
+	job = <-finished
+	if job.ID != running.ID || job.Status != StatusSucceeded || job.Result != "done" {
+		t.Errorf("Expected the succeeded job with its result, got %+v", job)
+	}
+
+	if err := m.Close(context.Background()); err != nil {
+		t.Errorf("Error closing manager: %v", err)
+	}
+}
+
+// Test Close cancels the context of finish hooks that are still running at
+// the deadline and waits for them to return
+func TestCloseCancelsHooks(t *testing.T) {
+	m := newTestManager(Config{Workers: 1, QueueSize: 10, Retention: time.Hour})
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	hook := func(ctx context.Context, job Job) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+	}
+	m.Submit(context.Background(), "analysis", "https://example.com", func(ctx context.Context) (interface{}, error) {
+		return "done", nil
+	}, hook)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to pass, got %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected Close to return after the hook")
+	}
+}
+
+// Test jobs that don't finish before shutdown are saved and resumed with
+// their IDs, while jobs that can't be resumed are canceled
+func TestCheckpointAndResume(t *testing.T) {
+	config := Config{Workers: 1, QueueSize: 10, Retention: time.Hour, CheckpointFile: filepath.Join(t.TempDir(), "jobs.json")}
+
+	started := make(chan string, 3)
+	var finished []Job
+	var mu sync.Mutex
+	register := func(m *Manager, block bool) {
+		m.Register("analysis", func(params json.RawMessage) (Task, []FinishHook, error) {
+			var target string
+			if err := json.Unmarshal(params, &target); err != nil {
+				return nil, nil, err
+			}
+			task := func(ctx context.Context) (interface{}, error) {
+				started <- target
+				if block {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return target, nil
+			}
+			hook := func(ctx context.Context, job Job) {
+				mu.Lock()
+				defer mu.Unlock()
+				finished = append(finished, job)
+			}
+			return task, []FinishHook{hook}, nil
+		})
+	}
+
+	m := newTestManager(config)
+	register(m, true)
+	running, err := m.SubmitResumable(context.Background(), "analysis", "https://example.com/a", "https://example.com/a")
+	if err != nil {
+		t.Fatalf("Error submitting job: %v", err)
+	}
+	<-started
+	queued, _ := m.SubmitResumable(context.Background(), "analysis", "https://example.com/b", "https://example.com/b")
+	plain, _ := m.Submit(context.Background(), "analysis", "https://example.com/c", func(ctx context.Context) (interface{}, error) {
+		return "done", nil
+	})
+	unsaved, _ := m.SubmitUnsaved(context.Background(), "analysis", "https://example.com/d", "https://example.com/d")
+
+	// The running job blocks, so it is interrupted at the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to pass, got %v", err)
+	}
+	for _, id := range []string{plain.ID, unsaved.ID} {
+		if job, _ := m.Get(id); job.Status != StatusCanceled {
+			t.Errorf("Expected the job that can't be resumed to be canceled, got %s", job.Status)
+		}
+	}
+	mu.Lock()
+	if len(finished) != 1 || finished[0].ID != unsaved.ID {
+		t.Errorf("Expected finish hooks only for the unsaved job, got %+v", finished)
+	}
+	finished = nil
+	mu.Unlock()
+	if info, err := os.Stat(config.CheckpointFile); err != nil {
+		t.Fatalf("Expected a checkpoint file: %v", err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the checkpoint file to be private, got %s", info.Mode().Perm())
+	}
+
+	resumed := newTestManager(config)
+	defer resumed.Close(context.Background())
+	register(resumed, false)
+	n, err := resumed.Resume(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Error resuming jobs: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected 2 resumed jobs, got %d", n)
+	}
+	for i, want := range []Job{running, queued} {
+		if target := <-started; target != want.Target {
+			t.Errorf("Expected job %d to run %s, got %s", i, want.Target, target)
+		}
+		job := waitForStatus(t, resumed, want.ID)
+		if job.Status != StatusSucceeded || job.Result != want.Target {
+			t.Errorf("Expected resumed job %s to succeed, got %+v", want.ID, job)
+		}
+	}
+	if _, err := os.Stat(config.CheckpointFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the checkpoint file to be removed, got %v", err)
+	}
+}
+
+// Test resumed jobs get the current identity of their key, and jobs of keys
+// deleted since are dropped
+func TestResumeRechecksKeys(t *testing.T) {
+	config := Config{Workers: 1, QueueSize: 10, Retention: time.Hour, CheckpointFile: filepath.Join(t.TempDir(), "jobs.json")}
+	register := func(m *Manager) {
+		m.Register("analysis", func(params json.RawMessage) (Task, []FinishHook, error) {
+			return func(ctx context.Context) (interface{}, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}, nil, nil
+		})
+	}
+
+	m := newTestManager(config)
+	register(m)
+	kept, _ := m.SubmitResumable(auth.WithIdentity(context.Background(), &auth.Identity{Name: "ci", Scopes: []string{auth.ScopeAnalyze}}), "analysis", "https://example.com/a", "a")
+	m.SubmitResumable(auth.WithIdentity(context.Background(), &auth.Identity{Name: "gone", Scopes: []string{auth.ScopeAnalyze}}), "analysis", "https://example.com/b", "b")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.Close(ctx)
+
+	resumed := newTestManager(config)
+	defer resumed.Close(context.Background())
+	register(resumed)
+	authenticator := auth.NewAuthenticator(auth.Config{Keys: []auth.KeyConfig{
+		{Name: "ci", Key: "plain", Scopes: []string{auth.ScopeAnalyze, auth.ScopeCrawl}},
+	}}, nil)
+	n, err := resumed.Resume(context.Background(), authenticator)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected only the job of the remaining key to resume, got %d, %v", n, err)
+	}
+	job, err := resumed.Get(kept.ID)
+	if err != nil || job.Owner == nil || !job.Owner.Allows(auth.ScopeCrawl) {
+		t.Errorf("Expected the resumed job to have the current scopes of its key, got %+v, %v", job.Owner, err)
+	}
+}
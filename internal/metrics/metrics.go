@@ -5,47 +5,146 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var (
-	// AnalysisDuration tracks how long each analysis takes
-	AnalysisDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "webpage_analyzer_analysis_duration_seconds",
-		Help:    "How long the webpage analysis took in seconds",
-		Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30},
-	})
-
-	// AnalysisRequests counts the number of analysis requests
-	AnalysisRequests = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "webpage_analyzer_requests_total",
-		Help: "The total number of webpage analysis requests",
-	})
-
-	// AnalysisErrors counts the number of analysis errors
-	AnalysisErrors = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "webpage_analyzer_errors_total",
-		Help: "The total number of webpage analysis errors",
-	})
-
-	// LinkCounts tracks different types of links found
-	LinkCounts = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "webpage_analyzer_link_counts",
-		Help: "Number of different types of links found",
-	}, []string{"type"})
-
-	// HeadingCounts tracks the number of different heading levels
-	HeadingCounts = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "webpage_analyzer_heading_counts",
-		Help: "Number of different heading levels found",
-	}, []string{"level"})
-
-	// LoginFormCount tracks the number of login forms found
-	LoginFormCount = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "webpage_analyzer_login_forms_total",
-		Help: "The total number of login forms found",
-	})
-
-	// HTMLVersionCount tracks the number of different HTML versions
-	HTMLVersionCount = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "webpage_analyzer_html_versions_total",
-		Help: "The total number of different HTML versions encountered",
-	}, []string{"version"})
-)
+// Metrics holds the collectors of the application. Each instance registers
+// its own collectors, so several can live side by side, e.g. in tests.
+type Metrics struct {
+	// AnalysisDuration tracks how long each successful analysis takes
+	AnalysisDuration prometheus.Histogram
+
+	// StageDuration tracks how long each stage of an analysis takes:
+	// fetching the page, parsing it and checking its links
+	StageDuration *prometheus.HistogramVec
+
+	// PhaseDuration tracks how long each HTTP request phase takes, split by
+	// whether the request fetched the analyzed page or checked a link
+	PhaseDuration *prometheus.HistogramVec
+
+	// AnalysisRequests counts the analyses started
+	AnalysisRequests prometheus.Counter
+
+	// AnalysisErrors counts the failed analyses by error code
+	AnalysisErrors *prometheus.CounterVec
+
+	// Links counts the links found by type (internal or external)
+	Links *prometheus.CounterVec
+
+	// LinkChecks counts the checked links by status class (2xx, 3xx, 4xx,
+	// 5xx or error when no response was received)
+	LinkChecks *prometheus.CounterVec
+
+	// Headings counts the headings found by level
+	Headings *prometheus.CounterVec
+
+	// LoginForms counts the analyzed pages that have a login form
+	LoginForms prometheus.Counter
+
+	// HTMLVersions counts the analyzed pages by HTML version
+	HTMLVersions *prometheus.CounterVec
+
+	// WebhookDeliveries counts webhook delivery attempts by outcome
+	WebhookDeliveries *prometheus.CounterVec
+
+	// WebhookDeadLetters counts webhook deliveries that were given up on
+	WebhookDeadLetters prometheus.Counter
+
+	// AnalysesInFlight is the number of analyses running
+	AnalysesInFlight prometheus.Gauge
+
+	// AnalysisQueueDepth is the number of analyses waiting to be admitted
+	AnalysisQueueDepth prometheus.Gauge
+
+	// OutboundRequestsInFlight is the number of requests to analyzed sites
+	// in flight, across all analyses
+	OutboundRequestsInFlight prometheus.Gauge
+
+	// RateLimitRejections counts the requests rejected by the rate limiter by
+	// reason: rate or quota
+	RateLimitRejections *prometheus.CounterVec
+
+	// BuildInfo is always 1 and labels the running build
+	BuildInfo *prometheus.GaugeVec
+}
+
+// New creates the collectors and registers them with reg, with every name
+// starting with prefix and an underscore. A nil reg creates collectors that
+// work but aren't exported.
+func New(reg prometheus.Registerer, prefix string) *Metrics {
+	if reg != nil && prefix != "" {
+		reg = prometheus.WrapRegistererWithPrefix(prefix+"_", reg)
+	}
+	factory := promauto.With(reg)
+
+	return &Metrics{
+		AnalysisDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "analysis_duration_seconds",
+			Help:    "How long the webpage analysis took in seconds",
+			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30},
+		}),
+		StageDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "stage_duration_seconds",
+			Help:    "How long each stage of the analysis took in seconds",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"stage"}),
+		PhaseDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "phase_duration_seconds",
+			Help:    "How long each HTTP request phase took in seconds",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"target", "phase"}),
+		AnalysisRequests: factory.NewCounter(prometheus.CounterOpts{
+			Name: "requests_total",
+			Help: "The total number of webpage analysis requests",
+		}),
+		AnalysisErrors: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "errors_total",
+			Help: "The total number of failed webpage analyses by error code",
+		}, []string{"code"}),
+		Links: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "links_total",
+			Help: "The total number of links found by type",
+		}, []string{"type"}),
+		LinkChecks: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "link_checks_total",
+			Help: "The total number of checked links by response status class",
+		}, []string{"status_class"}),
+		Headings: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "headings_total",
+			Help: "The total number of headings found by level",
+		}, []string{"level"}),
+		LoginForms: factory.NewCounter(prometheus.CounterOpts{
+			Name: "login_forms_total",
+			Help: "The total number of analyzed pages with a login form",
+		}),
+		HTMLVersions: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "html_versions_total",
+			Help: "The total number of analyzed pages by HTML version",
+		}, []string{"version"}),
+		WebhookDeliveries: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "webhook_delivery_attempts_total",
+			Help: "The total number of webhook delivery attempts by outcome",
+		}, []string{"outcome"}),
+		WebhookDeadLetters: factory.NewCounter(prometheus.CounterOpts{
+			Name: "webhook_dead_letters_total",
+			Help: "The total number of webhook deliveries that failed after all retries",
+		}),
+		AnalysesInFlight: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "analyses_in_flight",
+			Help: "The number of webpage analyses running",
+		}),
+		AnalysisQueueDepth: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "analysis_queue_depth",
+			Help: "The number of webpage analyses waiting for a free slot",
+		}),
+		OutboundRequestsInFlight: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "outbound_requests_in_flight",
+			Help: "The number of requests to analyzed sites in flight",
+		}),
+		RateLimitRejections: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "rate_limit_rejections_total",
+			Help: "The total number of requests rejected by the rate limiter by reason",
+		}, []string{"reason"}),
+		BuildInfo: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "A metric with a constant value of 1 labeled by the version, commit and Go version of the build",
+		}, []string{"version", "commit", "go_version"}),
+	}
+}
@@ -0,0 +1,230 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"home24/internal/auth"
+	"home24/internal/metrics"
+)
+
+// How clients are told apart
+const (
+	KeyByIP     = "ip"
+	KeyByAPIKey = "apiKey"
+)
+
+// Why a request was rejected
+const (
+	ReasonRate  = "rate"
+	ReasonQuota = "quota"
+)
+
+// Clients that haven't been seen for this long are forgotten
+const idleTimeout = time.Hour
+
+// Config holds the rate limiting settings
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Every client has a bucket of Burst tokens that refills at
+	// RequestsPerMinute; each request takes a token
+	RequestsPerMinute float64 `yaml:"requestsPerMinute"`
+	Burst             int     `yaml:"burst"`
+
+	// DailyQuota is the number of requests a client may make per UTC day;
+	// 0 means no quota
+	DailyQuota int `yaml:"dailyQuota"`
+
+	// KeyBy is ip or apiKey. Clients are keyed by the name of the key they
+	// authenticated with. Requests that weren't authenticated, including
+	// all of them when authentication is off, are keyed by IP, as a key
+	// that wasn't checked could be made up for every request.
+	KeyBy string `yaml:"keyBy"`
+
+	// TrustedProxies is the number of reverse proxies in front of the server
+	// that append to the X-Forwarded-For header. The client IP is the entry
+	// the outermost of them added, counted from the right, since the entries
+	// left of it come from the client. 0 ignores the header.
+	TrustedProxies int `yaml:"trustedProxies"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		RequestsPerMinute: 30,
+		Burst:             10,
+		KeyBy:             KeyByIP,
+	}
+}
+
+// Validate checks the settings
+func (c Config) Validate() error {
+	var errs []error
+	if c.RequestsPerMinute <= 0 {
+		errs = append(errs, fmt.Errorf("requestsPerMinute must be positive, got %v", c.RequestsPerMinute))
+	}
+	if c.Burst < 1 {
+		errs = append(errs, fmt.Errorf("burst must be at least 1, got %d", c.Burst))
+	}
+	if c.DailyQuota < 0 {
+		errs = append(errs, fmt.Errorf("dailyQuota must not be negative, got %d", c.DailyQuota))
+	}
+	if c.TrustedProxies < 0 {
+		errs = append(errs, fmt.Errorf("trustedProxies must not be negative, got %d", c.TrustedProxies))
+	}
+	if c.KeyBy != KeyByIP && c.KeyBy != KeyByAPIKey {
+		errs = append(errs, fmt.Errorf("keyBy must be %q or %q, got %q", KeyByIP, KeyByAPIKey, c.KeyBy))
+	}
+	return errors.Join(errs...)
+}
+
+// Decision is the outcome of a request to the limiter
+type Decision struct {
+	Allowed bool
+
+	// Reason is ReasonRate or ReasonQuota when the request was rejected
+	Reason string
+
+	// RetryAfter is how long the client has to wait before its next request
+	// can be allowed
+	RetryAfter time.Duration
+}
+
+// client is the state kept for one client
+type client struct {
+	tokens   float64
+	updated  time.Time
+	day      time.Time
+	requests int
+}
+
+// Limiter limits the requests of every client with a token bucket and a
+// daily quota. It is safe for concurrent use.
+type Limiter struct {
+	config  Config
+	metrics *metrics.Metrics
+	now     func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*client
+	swept   time.Time
+}
+
+// NewLimiter creates a limiter recording rejections to the given metrics,
+// which may be nil
+func NewLimiter(config Config, m *metrics.Metrics) *Limiter {
+	if m == nil {
+		m = metrics.New(nil, "")
+	}
+	return &Limiter{
+		config:  config,
+		metrics: m,
+		now:     time.Now,
+		clients: make(map[string]*client),
+	}
+}
+
+// Allow takes a token for the client with the given key
+func (l *Limiter) Allow(key string) Decision {
+	var now = l.now()
+	var day = now.UTC().Truncate(24 * time.Hour)
+	var rate = l.config.RequestsPerMinute / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	var c, ok = l.clients[key]
+	if !ok {
+		c = &client{tokens: float64(l.config.Burst), updated: now, day: day}
+		l.clients[key] = c
+	}
+
+	// Refill the bucket for the time since the last request and start a new
+	// quota every day
+	c.tokens = math.Min(float64(l.config.Burst), c.tokens+now.Sub(c.updated).Seconds()*rate)
+	c.updated = now
+	if !c.day.Equal(day) {
+		c.day, c.requests = day, 0
+	}
+
+	if l.config.DailyQuota > 0 && c.requests >= l.config.DailyQuota {
+		return l.reject(ReasonQuota, day.Add(24*time.Hour).Sub(now))
+	}
+	if c.tokens < 1 {
+		return l.reject(ReasonRate, time.Duration((1-c.tokens)/rate*float64(time.Second)))
+	}
+	c.tokens--
+	c.requests++
+	return Decision{Allowed: true}
+}
+
+// reject counts a rejected request
+func (l *Limiter) reject(reason string, retryAfter time.Duration) Decision {
+	l.metrics.RateLimitRejections.WithLabelValues(reason).Inc()
+	return Decision{Reason: reason, RetryAfter: retryAfter}
+}
+
+// sweep forgets the clients that have been idle for a while, at most once
+// per idle timeout. The caller must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleTimeout {
+		return
+	}
+	l.swept = now
+	var today = now.UTC().Truncate(24 * time.Hour)
+	var rate = l.config.RequestsPerMinute / 60
+	for key, c := range l.clients {
+		// Only clients that would start over with a full bucket and an
+		// unused quota are forgotten, so forgetting them changes nothing
+		var idle = now.Sub(c.updated)
+		if idle >= idleTimeout && c.tokens+idle.Seconds()*rate >= float64(l.config.Burst) && !c.day.Equal(today) {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// ClientKey returns the key the requests of a client are limited by: the
+// name of its API key if the limiter is keyed by API key and the request was
+// authenticated, otherwise its IP address
+func (l *Limiter) ClientKey(req *http.Request) string {
+	return l.Key(req.Context(), req.RemoteAddr, req.Header.Values("X-Forwarded-For"))
+}
+
+// Key is ClientKey for any transport: the identity comes from the context
+// and the IP address from the remote address and the X-Forwarded-For values
+func (l *Limiter) Key(ctx context.Context, remoteAddr string, forwardedFor []string) string {
+	if l.config.KeyBy == KeyByAPIKey {
+		if identity := auth.FromContext(ctx); identity != nil {
+			return "name:" + identity.Name
+		}
+	}
+	return "ip:" + l.clientIP(remoteAddr, forwardedFor)
+}
+
+// clientIP returns the IP address of a client. Behind trusted proxies it is
+// the entry of X-Forwarded-For the outermost proxy added; a header with
+// fewer entries than proxies is ignored.
+func (l *Limiter) clientIP(remoteAddr string, forwardedFor []string) string {
+	if l.config.TrustedProxies > 0 {
+		var forwarded = strings.Split(strings.Join(forwardedFor, ","), ",")
+		if hop := len(forwarded) - l.config.TrustedProxies; hop >= 0 {
+			if ip := strings.TrimSpace(forwarded[hop]); ip != "" {
+				return ip
+			}
+		}
+	}
+	var host, _, err = net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
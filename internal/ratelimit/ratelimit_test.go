@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"home24/internal/auth"
+	"home24/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// This function creates a limiter with a clock the test moves forward
+func newTestLimiter(config Config) (*Limiter, *time.Time, *prometheus.Registry) {
+	var registry = prometheus.NewRegistry()
+	var limiter = NewLimiter(config, metrics.New(registry, "test"))
+	var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now, registry
+}
+
+// This function returns the number of rejections for a reason
+func rejections(t *testing.T, registry *prometheus.Registry, reason string) float64 {
+	var families, err = registry.Gather()
+	if err != nil {
+		t.Fatalf("Error gathering metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "test_rate_limit_rejections_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if m.GetLabel()[0].GetValue() == reason {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// Test a client can use its burst, then has to wait for the bucket to refill
+// while other clients are not affected
+func TestTokenBucket(t *testing.T) {
+	var limiter, now, registry = newTestLimiter(Config{RequestsPerMinute: 6, Burst: 3, KeyBy: KeyByIP})
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("a").Allowed {
+			t.Fatalf("Expected request %d of the burst to be allowed", i+1)
+		}
+	}
+	var decision = limiter.Allow("a")
+	if decision.Allowed || decision.Reason != ReasonRate {
+		t.Fatalf("Expected the request after the burst to be rate limited, got %+v", decision)
+	}
+	if decision.RetryAfter != 10*time.Second {
+		t.Errorf("Expected to retry after 10s, got %s", decision.RetryAfter)
+	}
+	if !limiter.Allow("b").Allowed {
+		t.Error("Expected another client to be allowed")
+	}
+
+	*now = now.Add(10 * time.Second)
+	if !limiter.Allow("a").Allowed {
+		t.Error("Expected a request to be allowed once a token was refilled")
+	}
+	if limiter.Allow("a").Allowed {
+		t.Error("Expected the refilled token to be used up")
+	}
+	if n := rejections(t, registry, ReasonRate); n != 2 {
+		t.Errorf("Expected 2 rate rejections, got %v", n)
+	}
+}
+
+// Test the daily quota rejects requests until the next UTC day
+func TestDailyQuota(t *testing.T) {
+	var limiter, now, registry = newTestLimiter(Config{RequestsPerMinute: 600, Burst: 10, DailyQuota: 2, KeyBy: KeyByIP})
+
+	limiter.Allow("a")
+	limiter.Allow("a")
+	var decision = limiter.Allow("a")
+	if decision.Allowed || decision.Reason != ReasonQuota {
+		t.Fatalf("Expected the quota to be exceeded, got %+v", decision)
+	}
+	if decision.RetryAfter != 12*time.Hour {
+		t.Errorf("Expected to retry at midnight in 12h, got %s", decision.RetryAfter)
+	}
+
+	*now = now.Add(12 * time.Hour)
+	if !limiter.Allow("a").Allowed {
+		t.Error("Expected a new quota on the next day")
+	}
+	if n := rejections(t, registry, ReasonQuota); n != 1 {
+		t.Errorf("Expected 1 quota rejection, got %v", n)
+	}
+}
+
+// Test idle clients are forgotten only when that doesn't reset their limits
+func TestSweep(t *testing.T) {
+	var limiter, now, _ = newTestLimiter(Config{RequestsPerMinute: 60, Burst: 5, DailyQuota: 100, KeyBy: KeyByIP})
+
+	limiter.Allow("a")
+	*now = now.Add(2 * time.Hour)
+	limiter.Allow("b")
+	if _, ok := limiter.clients["a"]; !ok {
+		t.Error("Expected a client with a quota in use today to be kept")
+	}
+
+	*now = now.Add(24 * time.Hour)
+	limiter.Allow("c")
+	if len(limiter.clients) != 1 {
+		t.Errorf("Expected only the new client to be kept, got %d clients", len(limiter.clients))
+	}
+}
+
+// Test clients are keyed by authenticated API key or by IP address
+func TestClientKey(t *testing.T) {
+	var tests = []struct {
+		config    Config
+		apiKey    string
+		forwarded string
+		expected  string
+	}{
+		{Config{KeyBy: KeyByIP}, "secret", "", "ip:192.0.2.1"},
+		{Config{KeyBy: KeyByAPIKey}, "secret", "", "ip:192.0.2.1"},
+		{Config{KeyBy: KeyByAPIKey}, "", "", "ip:192.0.2.1"},
+		{Config{KeyBy: KeyByIP}, "", "198.51.100.7, 10.0.0.1", "ip:192.0.2.1"},
+		{Config{KeyBy: KeyByIP, TrustedProxies: 1}, "", "198.51.100.7, 10.0.0.1", "ip:10.0.0.1"},
+		{Config{KeyBy: KeyByIP, TrustedProxies: 2}, "", "198.51.100.7, 10.0.0.1", "ip:198.51.100.7"},
+		{Config{KeyBy: KeyByIP, TrustedProxies: 3}, "", "198.51.100.7, 10.0.0.1", "ip:192.0.2.1"},
+		{Config{KeyBy: KeyByIP, TrustedProxies: 1}, "", "", "ip:192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		var req = httptest.NewRequest("POST", "/api/v1/analyze", nil)
+		req.RemoteAddr = "192.0.2.1:54321"
+		if test.apiKey != "" {
+			req.Header.Set(auth.APIKeyHeader, test.apiKey)
+		}
+		if test.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if key := NewLimiter(test.config, nil).ClientKey(req); key != test.expected {
+			t.Errorf("Expected %q for %+v, got %q", test.expected, test, key)
+		}
+	}
+
+	// Authenticated clients are keyed by the name of their key
+	var req = httptest.NewRequest("POST", "/api/v1/analyze", nil)
+	req.Header.Set(auth.APIKeyHeader, "secret")
+	req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Name: "ci"}))
+	if key := NewLimiter(Config{KeyBy: KeyByAPIKey}, nil).ClientKey(req); key != "name:ci" {
+		t.Errorf("Expected the name of the key, got %q", key)
+	}
+}
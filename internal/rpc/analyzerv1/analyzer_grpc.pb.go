@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: analyzer/v1/analyzer.proto
+
+package analyzerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PageAnalyzerService_Analyze_FullMethodName       = "/analyzer.v1.PageAnalyzerService/Analyze"
+	PageAnalyzerService_AnalyzeStream_FullMethodName = "/analyzer.v1.PageAnalyzerService/AnalyzeStream"
+)
+
+// PageAnalyzerServiceClient is the client API for PageAnalyzerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PageAnalyzerService analyzes web pages
+type PageAnalyzerServiceClient interface {
+	// Analyze fetches and analyzes a page and returns the complete result
+	Analyze(ctx context.Context, in *AnalyzeRequest, opts ...grpc.CallOption) (*AnalyzeResponse, error)
+	// AnalyzeStream analyzes a page and streams its progress. The last event
+	// has type EVENT_TYPE_DONE and carries the complete result.
+	AnalyzeStream(ctx context.Context, in *AnalyzeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AnalyzeEvent], error)
+}
+
+type pageAnalyzerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPageAnalyzerServiceClient(cc grpc.ClientConnInterface) PageAnalyzerServiceClient {
+	return &pageAnalyzerServiceClient{cc}
+}
+
+func (c *pageAnalyzerServiceClient) Analyze(ctx context.Context, in *AnalyzeRequest, opts ...grpc.CallOption) (*AnalyzeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnalyzeResponse)
+	err := c.cc.Invoke(ctx, PageAnalyzerService_Analyze_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pageAnalyzerServiceClient) AnalyzeStream(ctx context.Context, in *AnalyzeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AnalyzeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PageAnalyzerService_ServiceDesc.Streams[0], PageAnalyzerService_AnalyzeStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AnalyzeRequest, AnalyzeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PageAnalyzerService_AnalyzeStreamClient = grpc.ServerStreamingClient[AnalyzeEvent]
+
+// PageAnalyzerServiceServer is the server API for PageAnalyzerService service.
+// All implementations must embed UnimplementedPageAnalyzerServiceServer
+// for forward compatibility.
+//
+// PageAnalyzerService analyzes web pages
+type PageAnalyzerServiceServer interface {
+	// Analyze fetches and analyzes a page and returns the complete result
+	Analyze(context.Context, *AnalyzeRequest) (*AnalyzeResponse, error)
+	// AnalyzeStream analyzes a page and streams its progress. The last event
+	// has type EVENT_TYPE_DONE and carries the complete result.
+	AnalyzeStream(*AnalyzeRequest, grpc.ServerStreamingServer[AnalyzeEvent]) error
+	mustEmbedUnimplementedPageAnalyzerServiceServer()
+}
+
+// UnimplementedPageAnalyzerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPageAnalyzerServiceServer struct{}
+
+func (UnimplementedPageAnalyzerServiceServer) Analyze(context.Context, *AnalyzeRequest) (*AnalyzeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Analyze not implemented")
+}
+func (UnimplementedPageAnalyzerServiceServer) AnalyzeStream(*AnalyzeRequest, grpc.ServerStreamingServer[AnalyzeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method AnalyzeStream not implemented")
+}
+func (UnimplementedPageAnalyzerServiceServer) mustEmbedUnimplementedPageAnalyzerServiceServer() {}
+func (UnimplementedPageAnalyzerServiceServer) testEmbeddedByValue()                             {}
+
+// UnsafePageAnalyzerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PageAnalyzerServiceServer will
+// result in compilation errors.
+type UnsafePageAnalyzerServiceServer interface {
+	mustEmbedUnimplementedPageAnalyzerServiceServer()
+}
+
+func RegisterPageAnalyzerServiceServer(s grpc.ServiceRegistrar, srv PageAnalyzerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPageAnalyzerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PageAnalyzerService_ServiceDesc, srv)
+}
+
+func _PageAnalyzerService_Analyze_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalyzeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PageAnalyzerServiceServer).Analyze(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PageAnalyzerService_Analyze_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PageAnalyzerServiceServer).Analyze(ctx, req.(*AnalyzeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PageAnalyzerService_AnalyzeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AnalyzeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PageAnalyzerServiceServer).AnalyzeStream(m, &grpc.GenericServerStream[AnalyzeRequest, AnalyzeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PageAnalyzerService_AnalyzeStreamServer = grpc.ServerStreamingServer[AnalyzeEvent]
+
+// PageAnalyzerService_ServiceDesc is the grpc.ServiceDesc for PageAnalyzerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PageAnalyzerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "analyzer.v1.PageAnalyzerService",
+	HandlerType: (*PageAnalyzerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Analyze",
+			Handler:    _PageAnalyzerService_Analyze_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AnalyzeStream",
+			Handler:       _PageAnalyzerService_AnalyzeStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "analyzer/v1/analyzer.proto",
+}
@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"home24/internal/analyzer"
+	"home24/internal/rpc/analyzerv1"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The protobuf event types by analyzer event type
+var eventTypes = map[analyzer.EventType]analyzerv1.EventType{
+	analyzer.EventFetched:     analyzerv1.EventType_EVENT_TYPE_FETCHED,
+	analyzer.EventParsed:      analyzerv1.EventType_EVENT_TYPE_PARSED,
+	analyzer.EventLinkChecked: analyzerv1.EventType_EVENT_TYPE_LINK_CHECKED,
+	analyzer.EventDone:        analyzerv1.EventType_EVENT_TYPE_DONE,
+}
+
+// The protobuf error codes by analyzer error code
+var errorCodes = map[string]analyzerv1.ErrorCode{
+	analyzer.ErrInvalidURL:         analyzerv1.ErrorCode_ERROR_CODE_INVALID_URL,
+	analyzer.ErrFetchFailed:        analyzerv1.ErrorCode_ERROR_CODE_FETCH_FAILED,
+	analyzer.ErrParseFailed:        analyzerv1.ErrorCode_ERROR_CODE_PARSE_FAILED,
+	analyzer.ErrTimeout:            analyzerv1.ErrorCode_ERROR_CODE_TIMEOUT,
+	analyzer.ErrCanceled:           analyzerv1.ErrorCode_ERROR_CODE_CANCELED,
+	analyzer.ErrMaxLinksReached:    analyzerv1.ErrorCode_ERROR_CODE_MAX_LINKS_REACHED,
+	analyzer.ErrMaxDepthReached:    analyzerv1.ErrorCode_ERROR_CODE_MAX_DEPTH_REACHED,
+	analyzer.ErrInvalidCredentials: analyzerv1.ErrorCode_ERROR_CODE_INVALID_CREDENTIALS,
+	analyzer.ErrInvalidConfig:      analyzerv1.ErrorCode_ERROR_CODE_INVALID_CONFIG,
+	analyzer.ErrBlockedAddress:     analyzerv1.ErrorCode_ERROR_CODE_BLOCKED_ADDRESS,
+	analyzer.ErrOverloaded:         analyzerv1.ErrorCode_ERROR_CODE_OVERLOADED,
+}
+
+// toResult converts an analysis result to its protobuf message
+func toResult(r *analyzer.AnalysisResult) *analyzerv1.AnalysisResult {
+	if r == nil {
+		return nil
+	}
+
+	headings := make(map[string]int32, len(r.Headings))
+	for level, count := range r.Headings {
+		headings[level] = int32(count)
+	}
+	links := make([]*analyzerv1.LinkInfo, len(r.Links))
+	for i, link := range r.Links {
+		links[i] = toLink(link)
+	}
+
+	return &analyzerv1.AnalysisResult{
+		Url:             r.URL,
+		FinalUrl:        r.FinalURL,
+		StatusCode:      int32(r.StatusCode),
+		Canonical:       r.Canonical,
+		Title:           r.Title,
+		Headings:        headings,
+		Links:           links,
+		AccessibleLinks: int32(r.AccessibleLinks),
+		HasLoginForm:    r.HasLoginForm,
+		HtmlVersion:     r.HTMLVersion,
+		Timings:         toTimings(r.Timings),
+		Duration:        durationpb.New(r.Duration),
+	}
+}
+
+// toLink converts a link to its protobuf message
+func toLink(l analyzer.LinkInfo) *analyzerv1.LinkInfo {
+	return &analyzerv1.LinkInfo{
+		Url:        l.URL,
+		IsInternal: l.IsInternal,
+		Accessible: l.Accessible,
+		StatusCode: int32(l.StatusCode),
+		Timings:    toTimings(l.Timings),
+	}
+}
+
+// toTimings converts phase timings to their protobuf message
+func toTimings(t analyzer.PhaseTimings) *analyzerv1.PhaseTimings {
+	return &analyzerv1.PhaseTimings{
+		DnsLookup:       durationpb.New(t.DNSLookup),
+		TcpConnect:      durationpb.New(t.TCPConnect),
+		TlsHandshake:    durationpb.New(t.TLSHandshake),
+		TimeToFirstByte: durationpb.New(t.TimeToFirstByte),
+		Download:        durationpb.New(t.Download),
+		Total:           durationpb.New(t.Total),
+		ReusedConn:      t.ReusedConn,
+	}
+}
+
+// toEvent converts an analysis event to its protobuf message
+func toEvent(e analyzer.Event) *analyzerv1.AnalyzeEvent {
+	return &analyzerv1.AnalyzeEvent{
+		Type:         eventTypes[e.Type],
+		Url:          e.URL,
+		Time:         timestamppb.New(e.Time),
+		StatusCode:   int32(e.StatusCode),
+		Link:         e.Link,
+		Accessible:   e.Accessible,
+		LinksChecked: int32(e.LinksChecked),
+		LinksTotal:   int32(e.LinksTotal),
+		Result:       toResult(e.Result),
+	}
+}
+
+// fromCredentials converts request credentials to analyzer credentials
+func fromCredentials(c *analyzerv1.Credentials) analyzer.Credentials {
+	creds := analyzer.Credentials{
+		Hosts:       c.GetHosts(),
+		Headers:     c.GetHeaders(),
+		Cookies:     c.GetCookies(),
+		BearerToken: c.GetBearerToken(),
+	}
+	if auth := c.GetBasicAuth(); auth != nil {
+		creds.BasicAuth = &analyzer.BasicAuth{Username: auth.GetUsername(), Password: auth.GetPassword()}
+	}
+	if cert := c.GetClientCert(); cert != nil {
+		creds.ClientCert = &analyzer.ClientCertificate{CertPEM: cert.GetCertPem(), KeyPEM: cert.GetKeyPem()}
+	}
+	return creds
+}
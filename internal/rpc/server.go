@@ -0,0 +1,304 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/ratelimit"
+	"home24/internal/rpc/analyzerv1"
+	"home24/pkg/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// The number of events buffered between a streaming analysis and its client
+const eventBufferSize = 64
+
+// Server implements the PageAnalyzerService on top of a PageAnalyzer
+type Server struct {
+	analyzerv1.UnimplementedPageAnalyzerServiceServer
+	analyzer analyzer.PageAnalyzer
+	auth     *auth.Authenticator
+	limiter  *ratelimit.Limiter
+	log      *slog.Logger
+}
+
+// NewServer creates a new gRPC service backed by the analyzer. Its
+// interceptors authenticate the calls, which need an API key with the
+// analyze scope unless the authenticator is nil, and take a token from the
+// caller's bucket unless the limiter is nil.
+func NewServer(a analyzer.PageAnalyzer, authenticator *auth.Authenticator, limiter *ratelimit.Limiter, log *slog.Logger) *Server {
+	return &Server{
+		analyzer: a,
+		auth:     authenticator,
+		limiter:  limiter,
+		log:      log,
+	}
+}
+
+// NewGRPCServer creates a gRPC server with the analyzer service and the
+// reflection service registered, and the service's interceptors installed
+func NewGRPCServer(a analyzer.PageAnalyzer, authenticator *auth.Authenticator, limiter *ratelimit.Limiter, log *slog.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	service := NewServer(a, authenticator, limiter, log)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(service.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(service.StreamInterceptor),
+	)
+	server := grpc.NewServer(opts...)
+	analyzerv1.RegisterPageAnalyzerServiceServer(server, service)
+	reflection.Register(server)
+	return server
+}
+
+// UnaryInterceptor admits the unary calls of the analyzer service; calls to
+// other services such as reflection pass through
+func (s *Server) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !s.serves(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := s.admit(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor admits the streaming calls of the analyzer service;
+// calls to other services such as reflection pass through
+func (s *Server) StreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !s.serves(info.FullMethod) {
+		return handler(srv, stream)
+	}
+	ctx, err := s.admit(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &admittedStream{ServerStream: stream, ctx: ctx})
+}
+
+// admittedStream is a server stream carrying the context of its admission
+type admittedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements grpc.ServerStream
+func (s *admittedStream) Context() context.Context {
+	return s.ctx
+}
+
+// serves reports whether a method belongs to the analyzer service
+func (s *Server) serves(method string) bool {
+	return strings.HasPrefix(method, "/"+analyzerv1.PageAnalyzerService_ServiceDesc.ServiceName+"/")
+}
+
+// admit gives the call an ID from the x-request-id metadata, or a new one,
+// which is sent back in the header and added to its log lines. It then
+// authenticates the call and takes a token from the caller's bucket.
+func (s *Server) admit(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var given string
+	if values := md.Get(logger.RequestIDHeader); len(values) > 0 {
+		given = values[0]
+	}
+	id := logger.RequestID(given)
+	grpc.SetHeader(ctx, metadata.Pairs(logger.RequestIDHeader, id))
+	ctx = logger.WithAttrs(ctx, slog.String(logger.RequestIDKey, id))
+
+	ctx, err := s.authenticate(ctx, md)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limit(ctx, md, method); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// limit takes a token from the bucket of the caller, keyed like the HTTP
+// requests. Rejected calls fail with RESOURCE_EXHAUSTED and get the seconds
+// to wait in the retry-after header.
+func (s *Server) limit(ctx context.Context, md metadata.MD, method string) error {
+	if s.limiter == nil {
+		return nil
+	}
+
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	decision := s.limiter.Allow(s.limiter.Key(ctx, remoteAddr, md.Get("x-forwarded-for")))
+	if decision.Allowed {
+		return nil
+	}
+
+	// Round up so clients that wait exactly as long get a token
+	seconds := max(int(math.Ceil(decision.RetryAfter.Seconds())), 1)
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+	s.log.WarnContext(ctx, "call rejected by rate limiter",
+		slog.String("reason", decision.Reason),
+		slog.String("method", method),
+		slog.Int("retry_after_seconds", seconds),
+	)
+
+	if decision.Reason == ratelimit.ReasonQuota {
+		return status.Error(codes.ResourceExhausted, "daily quota exceeded, retry tomorrow")
+	}
+	return status.Error(codes.ResourceExhausted, "too many requests, retry later")
+}
+
+// Analyze implements PageAnalyzerService.Analyze
+func (s *Server) Analyze(ctx context.Context, req *analyzerv1.AnalyzeRequest) (*analyzerv1.AnalyzeResponse, error) {
+	ctx, err := s.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.analyzer.Analyze(ctx, req.GetUrl())
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return &analyzerv1.AnalyzeResponse{Result: toResult(result)}, nil
+}
+
+// AnalyzeStream implements PageAnalyzerService.AnalyzeStream. The analysis
+// runs in its own goroutine and hands its events to the stream; a slow
+// client slows the analysis down rather than losing events.
+func (s *Server) AnalyzeStream(req *analyzerv1.AnalyzeRequest, stream grpc.ServerStreamingServer[analyzerv1.AnalyzeEvent]) error {
+	ctx, err := s.prepare(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan analyzer.Event, eventBufferSize)
+	ctx = analyzer.WithEventHandler(ctx, func(event analyzer.Event) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.analyzer.Analyze(ctx, req.GetUrl())
+		close(events)
+		done <- err
+	}()
+
+	for event := range events {
+		if err := stream.Send(toEvent(event)); err != nil {
+			// The client went away; stop the analysis and let it drain
+			cancel()
+			for range events {
+			}
+			<-done
+			return err
+		}
+	}
+
+	if err := <-done; err != nil {
+		return s.statusError(ctx, err)
+	}
+	return nil
+}
+
+// prepare validates the request of an admitted call and attaches its
+// credentials to the context
+func (s *Server) prepare(ctx context.Context, req *analyzerv1.AnalyzeRequest) (context.Context, error) {
+	u, err := url.Parse(req.GetUrl())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, newStatus(codes.InvalidArgument, analyzer.ErrInvalidURL, "url must be an absolute http or https URL")
+	}
+	if req.GetCredentials() != nil {
+		ctx = analyzer.WithCredentials(ctx, fromCredentials(req.GetCredentials()))
+	}
+	return ctx, nil
+}
+
+// authenticate checks the API key of the call, sent in the x-api-key or the
+// authorization metadata, and attributes the call to it
+func (s *Server) authenticate(ctx context.Context, md metadata.MD) (context.Context, error) {
+	if s.auth == nil {
+		return ctx, nil
+	}
+
+	var key string
+	if values := md.Get(auth.APIKeyHeader); len(values) > 0 {
+		key = values[0]
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		key = strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+	}
+
+	identity, err := s.auth.Authenticate(ctx, key)
+	if errors.Is(err, auth.ErrMissingKey) || errors.Is(err, auth.ErrInvalidKey) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "error authenticating call", slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	if !identity.Allows(auth.ScopeAnalyze) {
+		return nil, status.Error(codes.PermissionDenied, "the API key needs the "+auth.ScopeAnalyze+" scope")
+	}
+
+	ctx = auth.WithIdentity(ctx, identity)
+	return logger.WithAttrs(ctx, slog.String("api_key", identity.Name)), nil
+}
+
+// statusError converts an analysis error to a gRPC status error carrying an
+// AnalysisError detail
+func (s *Server) statusError(ctx context.Context, err error) error {
+	var analysisErr *analyzer.AnalysisError
+	if !errors.As(err, &analysisErr) {
+		s.log.ErrorContext(ctx, "grpc analysis failed", slog.String("error", err.Error()))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	message := analysisErr.Message
+	if analysisErr.Err != nil {
+		message += ": " + analysisErr.Err.Error()
+	}
+	return newStatus(codeForErrorCode(analysisErr.Code), analysisErr.Code, message)
+}
+
+// newStatus builds a status error with the analysis error attached as detail
+func newStatus(code codes.Code, errorCode, message string) error {
+	st := status.New(code, message)
+	detailed, err := st.WithDetails(&analyzerv1.AnalysisError{Code: errorCodes[errorCode], Message: message})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// codeForErrorCode maps analysis error codes to gRPC status codes
+func codeForErrorCode(code string) codes.Code {
+	switch code {
+	case analyzer.ErrInvalidURL, analyzer.ErrInvalidCredentials, analyzer.ErrBlockedAddress:
+		return codes.InvalidArgument
+	case analyzer.ErrFetchFailed, analyzer.ErrOverloaded:
+		return codes.Unavailable
+	case analyzer.ErrTimeout:
+		return codes.DeadlineExceeded
+	case analyzer.ErrCanceled:
+		return codes.Canceled
+	case analyzer.ErrParseFailed, analyzer.ErrMaxLinksReached, analyzer.ErrMaxDepthReached:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,210 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+	"home24/internal/ratelimit"
+	"home24/internal/rpc/analyzerv1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the analyzer over an in-memory connection, requiring
+// API keys if the authenticator isn't nil and limiting calls if the limiter
+// isn't nil
+func newTestClient(t *testing.T, authenticator *auth.Authenticator, limiter *ratelimit.Limiter) analyzerv1.PageAnalyzerServiceClient {
+	t.Helper()
+
+	config := analyzer.DefaultConfig()
+	config.Network.AllowedNetworks = []string{"127.0.0.0/8"}
+	config.RetryAttempts = 1
+	server := NewGRPCServer(analyzer.NewDefaultPageAnalyzer(&config, nil), authenticator, limiter, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return analyzerv1.NewPageAnalyzerServiceClient(conn)
+}
+
+// newTestSite serves a page with one working and one broken link
+func newTestSite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	site := httptest.NewServer(mux)
+	t.Cleanup(site.Close)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<!DOCTYPE html><html><head><title>Test</title></head><body>
+			<h1>Test</h1><a href="/ok">ok</a><a href="/missing">missing</a></body></html>`)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	return site
+}
+
+// Test the unary call returns the complete result
+func TestAnalyze(t *testing.T) {
+	client := newTestClient(t, nil, nil)
+	site := newTestSite(t)
+
+	resp, err := client.Analyze(context.Background(), &analyzerv1.AnalyzeRequest{Url: site.URL + "/"})
+	if err != nil {
+		t.Fatalf("Error analyzing: %v", err)
+	}
+
+	result := resp.GetResult()
+	if result.GetTitle() != "Test" || result.GetStatusCode() != 200 || result.GetHtmlVersion() != "HTML 5" {
+		t.Errorf("Unexpected result %v", result)
+	}
+	if len(result.GetLinks()) != 2 || result.GetAccessibleLinks() != 1 {
+		t.Errorf("Expected 2 links of which 1 accessible, got %v", result.GetLinks())
+	}
+	if result.GetHeadings()["h1"] != 1 {
+		t.Errorf("Expected one h1, got %v", result.GetHeadings())
+	}
+}
+
+// Test the stream reports link checks and ends with the result
+func TestAnalyzeStream(t *testing.T) {
+	client := newTestClient(t, nil, nil)
+	site := newTestSite(t)
+
+	stream, err := client.AnalyzeStream(context.Background(), &analyzerv1.AnalyzeRequest{Url: site.URL + "/"})
+	if err != nil {
+		t.Fatalf("Error starting stream: %v", err)
+	}
+
+	var events []*analyzerv1.AnalyzeEvent
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error receiving: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 5 {
+		t.Fatalf("Expected fetched, parsed, 2 link checks and done, got %d events", len(events))
+	}
+	if events[0].GetType() != analyzerv1.EventType_EVENT_TYPE_FETCHED || events[1].GetType() != analyzerv1.EventType_EVENT_TYPE_PARSED {
+		t.Errorf("Unexpected first events %v, %v", events[0].GetType(), events[1].GetType())
+	}
+	if events[3].GetType() != analyzerv1.EventType_EVENT_TYPE_LINK_CHECKED || events[3].GetLinksChecked() != 2 || events[3].GetLinksTotal() != 2 {
+		t.Errorf("Expected the second link check to report 2 of 2, got %v", events[3])
+	}
+	last := events[4]
+	if last.GetType() != analyzerv1.EventType_EVENT_TYPE_DONE || last.GetResult().GetAccessibleLinks() != 1 {
+		t.Errorf("Expected the done event with the final result, got %v", last)
+	}
+}
+
+// Test analysis errors map to gRPC status codes with the error detail
+func TestAnalyzeErrors(t *testing.T) {
+	client := newTestClient(t, nil, nil)
+
+	tests := []struct {
+		url  string
+		code codes.Code
+		want analyzerv1.ErrorCode
+	}{
+		{"not a url", codes.InvalidArgument, analyzerv1.ErrorCode_ERROR_CODE_INVALID_URL},
+		{"http://127.0.0.1:1/", codes.Unavailable, analyzerv1.ErrorCode_ERROR_CODE_FETCH_FAILED},
+	}
+
+	for _, test := range tests {
+		_, err := client.Analyze(context.Background(), &analyzerv1.AnalyzeRequest{Url: test.url})
+		st := status.Convert(err)
+		if st.Code() != test.code {
+			t.Errorf("%s: expected %v, got %v", test.url, test.code, st.Code())
+			continue
+		}
+		if len(st.Details()) != 1 {
+			t.Errorf("%s: expected an AnalysisError detail, got %v", test.url, st.Details())
+			continue
+		}
+		if detail, ok := st.Details()[0].(*analyzerv1.AnalysisError); !ok || detail.GetCode() != test.want {
+			t.Errorf("%s: expected %v, got %v", test.url, test.want, st.Details()[0])
+		}
+	}
+}
+
+// Test calls need an API key with the analyze scope when keys are required
+func TestAuthentication(t *testing.T) {
+	site := newTestSite(t)
+	client := newTestClient(t, auth.NewAuthenticator(auth.Config{Keys: []auth.KeyConfig{
+		{Name: "ci", Key: "analyze-key", Scopes: []string{auth.ScopeAnalyze}},
+		{Name: "reports", Key: "history-key", Scopes: []string{auth.ScopeReadHistory}},
+	}}, nil), nil)
+	req := &analyzerv1.AnalyzeRequest{Url: site.URL}
+
+	tests := []struct {
+		md       metadata.MD
+		expected codes.Code
+	}{
+		{nil, codes.Unauthenticated},
+		{metadata.Pairs("x-api-key", "wrong"), codes.Unauthenticated},
+		{metadata.Pairs("x-api-key", "history-key"), codes.PermissionDenied},
+		{metadata.Pairs("x-api-key", "analyze-key"), codes.OK},
+		{metadata.Pairs("authorization", "Bearer analyze-key"), codes.OK},
+	}
+	for _, tt := range tests {
+		ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+		_, err := client.Analyze(ctx, req)
+		if code := status.Code(err); code != tt.expected {
+			t.Errorf("Expected %s with %v, got %v", tt.expected, tt.md, err)
+		}
+	}
+}
+
+// Test unary and streaming calls take tokens from the same bucket
+func TestRateLimit(t *testing.T) {
+	site := newTestSite(t)
+	client := newTestClient(t, nil, ratelimit.NewLimiter(ratelimit.Config{RequestsPerMinute: 1, Burst: 1, KeyBy: ratelimit.KeyByIP}, nil))
+	req := &analyzerv1.AnalyzeRequest{Url: site.URL}
+
+	if _, err := client.Analyze(context.Background(), req); err != nil {
+		t.Fatalf("Expected the first call to pass, got %v", err)
+	}
+
+	var header metadata.MD
+	_, err := client.Analyze(context.Background(), req, grpc.Header(&header))
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("Expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+	if retryAfter := header.Get("retry-after"); len(retryAfter) != 1 || retryAfter[0] != "60" {
+		t.Errorf("Expected to retry after 60 seconds, got %v", retryAfter)
+	}
+
+	stream, err := client.AnalyzeStream(context.Background(), req)
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Errorf("Expected the stream to be limited too, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package sitemap
+
+import (
+	"strings"
+	"time"
+)
+
+// The W3C Datetime formats allowed for lastmod, from the least to the most
+// precise. Fractional seconds are accepted by the last layout as well.
+var lastModLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+// How far a lastmod value may lie ahead of the current time before it is
+// reported. Dates without a time have no time zone, so they get enough slack
+// for every zone.
+const (
+	clockSkew    = 5 * time.Minute
+	timeZoneSkew = 14 * time.Hour
+)
+
+// parseLastMod parses a lastmod value in W3C Datetime format. The boolean
+// reports whether the value carried a time of day.
+func parseLastMod(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	var err error
+	for _, layout := range lastModLayouts {
+		var t time.Time
+		t, err = time.Parse(layout, value)
+		if err == nil {
+			return t, strings.Contains(layout, "T"), nil
+		}
+	}
+	return time.Time{}, false, err
+}
+
+// checkLastMod reports the issue with a lastmod value, if any
+func checkLastMod(value string, now time.Time) *Issue {
+	if value == "" {
+		return nil
+	}
+
+	t, hasTime, err := parseLastMod(value)
+	if err != nil {
+		return &Issue{Code: IssueLastModMalformed, Message: "lastmod " + value + " is not a W3C Datetime value"}
+	}
+
+	skew := clockSkew
+	if !hasTime {
+		skew = timeZoneSkew
+	}
+	if t.After(now.Add(skew)) {
+		return &Issue{Code: IssueLastModFuture, Message: "lastmod " + value + " is in the future"}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package sitemap
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// The maximum size of a robots.txt file that is read
+const maxRobotsBytes = 500 << 10
+
+// robotsRule is a single Allow or Disallow line
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsGroup holds the rules for a set of user agents
+type robotsGroup struct {
+	agents []string
+	rules  []robotsRule
+}
+
+// robots holds the rules of a robots.txt file that apply to one user agent
+type robots struct {
+	rules []robotsRule
+}
+
+// parseRobots reads a robots.txt file and keeps the group that applies to
+// the user agent. A group naming the agent's product token wins over the
+// "*" group; groups for the same agent are merged.
+func parseRobots(r io.Reader, userAgent string) *robots {
+	token := strings.ToLower(productToken(userAgent))
+
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsBytes))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+		}
+	}
+
+	var specific, wildcard []robotsRule
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			switch {
+			case agent == token && token != "":
+				specific = append(specific, group.rules...)
+			case agent == "*":
+				wildcard = append(wildcard, group.rules...)
+			}
+		}
+	}
+	if specific != nil {
+		return &robots{rules: specific}
+	}
+	return &robots{rules: wildcard}
+}
+
+// Allowed reports whether the path (including the query) may be crawled. The
+// longest matching rule wins and Allow wins a tie.
+func (r *robots) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// matchRobotsPattern matches a path against a rule pattern, where "*"
+// matches any sequence of characters and a trailing "$" anchors the end
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if !anchored {
+		return true
+	}
+	// The last part has to sit at the very end of the path
+	last := parts[len(parts)-1]
+	return rest == "" || (len(parts) > 1 && strings.HasSuffix(path, last))
+}
+
+// productToken returns the name part of a User-Agent, e.g. "WebPageAnalyzer"
+// for "WebPageAnalyzer/1.0"
+func productToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
@@ -0,0 +1,393 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/batch"
+)
+
+// The maximum uncompressed size of a sitemap file, as set by the sitemaps protocol
+const maxSitemapBytes = 50 << 20
+
+// Issue codes reported for sitemap entries
+const (
+	IssueNon200            = "NON_200"
+	IssueRedirect          = "REDIRECT"
+	IssueCanonicalMismatch = "CANONICAL_MISMATCH"
+	IssueRobotsBlocked     = "ROBOTS_BLOCKED"
+	IssueLastModMalformed  = "LASTMOD_MALFORMED"
+	IssueLastModFuture     = "LASTMOD_FUTURE"
+	IssueAnalysisFailed    = "ANALYSIS_FAILED"
+)
+
+// Config holds the sitemap analysis settings
+type Config struct {
+	MaxURLs       int `yaml:"maxURLs"`
+	MaxIndexDepth int `yaml:"maxIndexDepth"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		MaxURLs:       500,
+		MaxIndexDepth: 3,
+	}
+}
+
+// Fetcher downloads the sitemap documents and robots.txt.
+// DefaultPageAnalyzer satisfies it.
+type Fetcher interface {
+	Get(ctx context.Context, url string) (*http.Response, error)
+	UserAgent() string
+}
+
+// Issue is a problem found with a sitemap entry
+type Issue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Entry is a URL listed in a sitemap together with the issues found for it
+type Entry struct {
+	URL        string  `json:"url"`
+	LastMod    string  `json:"lastmod,omitempty"`
+	Sitemap    string  `json:"sitemap"`
+	StatusCode int     `json:"status_code,omitempty"`
+	FinalURL   string  `json:"final_url,omitempty"`
+	Canonical  string  `json:"canonical,omitempty"`
+	Issues     []Issue `json:"issues"`
+}
+
+// File is a sitemap or sitemap index that was read
+type File struct {
+	URL   string `json:"url"`
+	Index bool   `json:"index"`
+	URLs  int    `json:"urls"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report holds the validation of a sitemap and the analysis of its pages
+type Report struct {
+	SitemapURL  string         `json:"sitemap_url"`
+	Files       []File         `json:"files"`
+	Truncated   bool           `json:"truncated"`
+	Entries     []Entry        `json:"entries"`
+	IssueCounts map[string]int `json:"issue_counts"`
+	Analysis    *batch.Report  `json:"analysis"`
+	Duration    time.Duration  `json:"duration_ns"`
+}
+
+// Checker reads sitemaps, validates their entries and analyzes the pages
+type Checker struct {
+	fetcher     Fetcher
+	analyzer    analyzer.PageAnalyzer
+	config      Config
+	concurrency int
+	now         func() time.Time
+}
+
+// NewChecker creates a new sitemap checker that reads sitemaps with the
+// fetcher and analyzes at most concurrency pages at once
+func NewChecker(config Config, fetcher Fetcher, a analyzer.PageAnalyzer, concurrency int) *Checker {
+	return &Checker{
+		fetcher:     fetcher,
+		analyzer:    a,
+		config:      config,
+		concurrency: concurrency,
+		now:         time.Now,
+	}
+}
+
+// Run reads the sitemap, following nested sitemap indexes, and analyzes every
+// listed page that robots.txt allows. It only fails if the sitemap itself
+// cannot be read; problems with nested sitemaps are recorded in the report.
+func (c *Checker) Run(ctx context.Context, sitemapURL string) (*Report, error) {
+	start := time.Now()
+	report := &Report{
+		SitemapURL:  sitemapURL,
+		Files:       []File{},
+		Entries:     []Entry{},
+		IssueCounts: make(map[string]int),
+	}
+
+	l := &loader{checker: c, report: report, visited: make(map[string]bool), seen: make(map[string]bool)}
+	if err := l.load(ctx, sitemapURL, 0); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, analyzer.NewAnalysisError(analyzer.ErrCanceled, "sitemap check canceled", ctx.Err())
+	}
+
+	// Validate the entries and leave out the ones robots.txt blocks
+	now := c.now()
+	rules := make(map[string]*robots)
+	var urls []string
+	for i := range report.Entries {
+		entry := &report.Entries[i]
+		if issue := checkLastMod(entry.LastMod, now); issue != nil {
+			entry.Issues = append(entry.Issues, *issue)
+		}
+
+		u, _ := url.Parse(entry.URL)
+		origin := u.Scheme + "://" + u.Host
+		if _, ok := rules[origin]; !ok {
+			rules[origin] = c.fetchRobots(ctx, origin)
+		}
+		if !rules[origin].Allowed(u.RequestURI()) {
+			entry.Issues = append(entry.Issues, Issue{Code: IssueRobotsBlocked, Message: "blocked by " + origin + "/robots.txt"})
+			continue
+		}
+		urls = append(urls, entry.URL)
+	}
+
+	report.Analysis = batch.Run(ctx, c.analyzer, urls, c.concurrency)
+	pages := make(map[string]batch.PageReport, len(report.Analysis.Pages))
+	for _, page := range report.Analysis.Pages {
+		pages[page.URL] = page
+	}
+
+	for i := range report.Entries {
+		entry := &report.Entries[i]
+		page, ok := pages[entry.URL]
+		if !ok {
+			continue
+		}
+		entry.Issues = append(entry.Issues, pageIssues(entry, page)...)
+	}
+
+	for _, entry := range report.Entries {
+		for _, issue := range entry.Issues {
+			report.IssueCounts[issue.Code]++
+		}
+	}
+	report.Duration = time.Since(start)
+
+	if ctx.Err() != nil {
+		return nil, analyzer.NewAnalysisError(analyzer.ErrCanceled, "sitemap check canceled", ctx.Err())
+	}
+	return report, nil
+}
+
+// pageIssues compares the analysis of a page with its sitemap entry. A page
+// whose analysis failed on its response status is reported as NON_200.
+func pageIssues(entry *Entry, page batch.PageReport) []Issue {
+	if page.Error != nil && page.Error.StatusCode != 0 {
+		entry.StatusCode = page.Error.StatusCode
+		return []Issue{{Code: IssueNon200, Message: fmt.Sprintf("returned status %d", page.Error.StatusCode)}}
+	}
+	if page.Error != nil {
+		return []Issue{{Code: IssueAnalysisFailed, Message: page.Error.Message}}
+	}
+
+	var issues []Issue
+	result := page.Result
+	entry.StatusCode = result.StatusCode
+	entry.FinalURL = result.FinalURL
+	entry.Canonical = result.Canonical
+
+	if result.StatusCode != http.StatusOK {
+		issues = append(issues, Issue{Code: IssueNon200, Message: fmt.Sprintf("returned status %d", result.StatusCode)})
+	}
+	// A redirected entry is already reported, so the canonical URL is
+	// compared with the page it redirects to
+	landed := entry.URL
+	if result.FinalURL != "" && !sameURL(result.FinalURL, entry.URL) {
+		issues = append(issues, Issue{Code: IssueRedirect, Message: "redirects to " + result.FinalURL})
+		landed = result.FinalURL
+	}
+	if result.Canonical != "" && !sameURL(result.Canonical, landed) {
+		issues = append(issues, Issue{Code: IssueCanonicalMismatch, Message: "canonical URL is " + result.Canonical})
+	}
+	return issues
+}
+
+// sameURL compares two URLs ignoring the case of the scheme and host, the
+// default port and the fragment
+func sameURL(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return normalize(ua) == normalize(ub)
+}
+
+// normalize returns the comparable form of a URL
+func normalize(u *url.URL) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	if (n.Scheme == "http" && n.Port() == "80") || (n.Scheme == "https" && n.Port() == "443") {
+		n.Host = n.Hostname()
+	}
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	n.Fragment = ""
+	n.RawFragment = ""
+	return n.String()
+}
+
+// fetchRobots fetches the robots.txt of an origin. A missing or unreadable
+// file allows everything.
+func (c *Checker) fetchRobots(ctx context.Context, origin string) *robots {
+	resp, err := c.fetcher.Get(ctx, origin+"/robots.txt")
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	return parseRobots(resp.Body, c.fetcher.UserAgent())
+}
+
+// location is a <url> or <sitemap> element
+type location struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// document is either a <urlset> or a <sitemapindex>
+type document struct {
+	XMLName  xml.Name
+	URLs     []location `xml:"url"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+// loader walks a sitemap and its nested indexes
+type loader struct {
+	checker *Checker
+	report  *Report
+	visited map[string]bool
+	seen    map[string]bool
+}
+
+// load reads a sitemap file and adds its entries to the report. Only the
+// error of the top level sitemap is returned; nested failures are recorded
+// on the file.
+func (l *loader) load(ctx context.Context, sitemapURL string, depth int) error {
+	if l.visited[sitemapURL] || ctx.Err() != nil {
+		return nil
+	}
+	l.visited[sitemapURL] = true
+
+	// Nested loads grow Files, so the entry is addressed by index
+	index := len(l.report.Files)
+	l.report.Files = append(l.report.Files, File{URL: sitemapURL})
+
+	doc, err := l.fetch(ctx, sitemapURL)
+	if err != nil {
+		l.report.Files[index].Error = err.Error()
+		if depth == 0 {
+			return err
+		}
+		return nil
+	}
+
+	switch doc.XMLName.Local {
+	case "sitemapindex":
+		l.report.Files[index].Index = true
+		l.report.Files[index].URLs = len(doc.Sitemaps)
+		if depth >= l.checker.config.MaxIndexDepth {
+			l.report.Files[index].Error = fmt.Sprintf("sitemap indexes nested deeper than %d levels are not followed", l.checker.config.MaxIndexDepth)
+			return nil
+		}
+		for _, child := range doc.Sitemaps {
+			childURL, err := resolve(sitemapURL, child.Loc)
+			if err != nil {
+				l.report.Files = append(l.report.Files, File{URL: child.Loc, Error: err.Error()})
+				continue
+			}
+			l.load(ctx, childURL, depth+1)
+		}
+	case "urlset":
+		l.report.Files[index].URLs = len(doc.URLs)
+		for _, u := range doc.URLs {
+			l.addEntry(sitemapURL, u)
+		}
+	default:
+		l.report.Files[index].Error = "unexpected root element <" + doc.XMLName.Local + ">"
+		if depth == 0 {
+			return analyzer.NewAnalysisError(analyzer.ErrParseFailed, "not a sitemap: "+l.report.Files[index].Error, nil)
+		}
+	}
+	return nil
+}
+
+// addEntry adds a listed URL unless it is a duplicate or the limit is reached
+func (l *loader) addEntry(sitemapURL string, u location) {
+	loc, err := resolve(sitemapURL, u.Loc)
+	if err != nil || l.seen[loc] {
+		return
+	}
+	if max := l.checker.config.MaxURLs; max > 0 && len(l.report.Entries) >= max {
+		l.report.Truncated = true
+		return
+	}
+	l.seen[loc] = true
+	l.report.Entries = append(l.report.Entries, Entry{
+		URL:     loc,
+		LastMod: strings.TrimSpace(u.LastMod),
+		Sitemap: sitemapURL,
+		Issues:  []Issue{},
+	})
+}
+
+// fetch downloads and decodes a sitemap file. Gzipped files are recognized
+// by their content, as servers label them inconsistently.
+func (l *loader) fetch(ctx context.Context, sitemapURL string) (*document, error) {
+	resp, err := l.checker.fetcher.Get(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, analyzer.NewAnalysisError(analyzer.ErrFetchFailed, "sitemap returned "+resp.Status, nil)
+	}
+
+	var body io.Reader = bufio.NewReader(resp.Body)
+	magic, _ := body.(*bufio.Reader).Peek(2)
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, analyzer.NewAnalysisError(analyzer.ErrParseFailed, "invalid gzipped sitemap", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var doc document
+	if err := xml.NewDecoder(io.LimitReader(body, maxSitemapBytes)).Decode(&doc); err != nil {
+		return nil, analyzer.NewAnalysisError(analyzer.ErrParseFailed, "invalid sitemap XML", err)
+	}
+	return &doc, nil
+}
+
+// resolve turns a <loc> value into an absolute http or https URL
+func resolve(base, loc string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	u, err := baseURL.Parse(strings.TrimSpace(loc))
+	if err != nil {
+		return "", fmt.Errorf("invalid location %q: %w", loc, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid location %q", loc)
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
@@ -0,0 +1,198 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"home24/internal/analyzer"
+)
+
+// Test lastmod values are checked against the W3C Datetime formats
+func TestCheckLastMod(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		code  string
+	}{
+		{"", ""},
+		{"2024", ""},
+		{"2024-05", ""},
+		{"2024-05-31", ""},
+		{"2024-05-31T10:00+02:00", ""},
+		{"2024-05-31T10:00:00.123Z", ""},
+		{"2024-06-01", ""},
+		{"31/05/2024", IssueLastModMalformed},
+		{"2024-05-31 10:00:00", IssueLastModMalformed},
+		{"2024-13-01", IssueLastModMalformed},
+		{"2024-06-03", IssueLastModFuture},
+		{"2024-06-01T13:00:00Z", IssueLastModFuture},
+	}
+
+	for _, test := range tests {
+		issue := checkLastMod(test.value, now)
+		code := ""
+		if issue != nil {
+			code = issue.Code
+		}
+		if code != test.code {
+			t.Errorf("lastmod %q: expected %q, got %q", test.value, test.code, code)
+		}
+	}
+}
+
+// Test robots.txt groups and rule precedence
+func TestRobots(t *testing.T) {
+	rules := parseRobots(strings.NewReader(`
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: OtherBot
+Disallow: /
+`), "WebPageAnalyzer/1.0")
+
+	tests := map[string]bool{
+		"/":                   true,
+		"/private":            false,
+		"/private/page":       false,
+		"/private/public/a":   true,
+		"/docs/file.pdf":      false,
+		"/docs/file.pdf?x=1":  true,
+		"/docs/file.pdf.html": true,
+	}
+	for path, expected := range tests {
+		if rules.Allowed(path) != expected {
+			t.Errorf("path %s: expected allowed=%v", path, expected)
+		}
+	}
+
+	specific := parseRobots(strings.NewReader("User-agent: *\nDisallow: /\n\nUser-agent: webpageanalyzer\nDisallow: /admin\n"), "WebPageAnalyzer/1.0")
+	if !specific.Allowed("/page") || specific.Allowed("/admin") {
+		t.Error("Expected the group naming the analyzer to replace the * group")
+	}
+}
+
+// Test a gzipped sitemap index with nested sitemaps is read and every
+// entry is validated and analyzed
+func TestRun(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	page := func(canonical string) string {
+		return fmt.Sprintf(`<html><head><title>Page</title><link rel="canonical" href="%s"></head><body></body></html>`, canonical)
+	}
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/sitemap_index.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		fmt.Fprintf(gz, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemap_pages.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/sitemap_missing.xml</loc></sitemap>
+</sitemapindex>`, server.URL)
+		gz.Close()
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/sitemap_pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/ok</loc><lastmod>2024-01-01</lastmod></url>
+  <url><loc>%[1]s/gone</loc></url>
+  <url><loc>%[1]s/down</loc></url>
+  <url><loc>%[1]s/moved</loc></url>
+  <url><loc>%[1]s/duplicate</loc><lastmod>yesterday</lastmod></url>
+  <url><loc>%[1]s/private/page</loc><lastmod>2999-01-01</lastmod></url>
+  <url><loc>%[1]s/ok</loc></url>
+</urlset>`, server.URL)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page(server.URL+"/ok"))
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, page(""))
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/duplicate", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page("/ok"))
+	})
+	mux.HandleFunc("/private/page", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the page blocked by robots.txt not to be fetched")
+	})
+
+	config := analyzer.DefaultConfig()
+	config.Network.AllowedNetworks = []string{"127.0.0.0/8"}
+	config.RetryAttempts = 1
+	pageAnalyzer := analyzer.NewDefaultPageAnalyzer(&config, nil)
+	checker := NewChecker(DefaultConfig(), pageAnalyzer, pageAnalyzer, 2)
+	report, err := checker.Run(context.Background(), server.URL+"/sitemap_index.xml.gz")
+	if err != nil {
+		t.Fatalf("Error running sitemap check: %v", err)
+	}
+
+	if len(report.Files) != 3 || !report.Files[0].Index || report.Files[2].Error == "" {
+		t.Errorf("Expected the index, one sitemap and one failing sitemap, got %+v", report.Files)
+	}
+	if len(report.Entries) != 6 {
+		t.Fatalf("Expected 6 unique entries, got %d", len(report.Entries))
+	}
+
+	expected := map[string][]string{
+		"/ok":           nil,
+		"/gone":         {IssueNon200},
+		"/down":         {IssueNon200},
+		"/moved":        {IssueRedirect},
+		"/duplicate":    {IssueLastModMalformed, IssueCanonicalMismatch},
+		"/private/page": {IssueLastModFuture, IssueRobotsBlocked},
+	}
+	for _, entry := range report.Entries {
+		path := strings.TrimPrefix(entry.URL, server.URL)
+		var codes []string
+		for _, issue := range entry.Issues {
+			codes = append(codes, issue.Code)
+		}
+		if fmt.Sprint(codes) != fmt.Sprint(expected[path]) {
+			t.Errorf("Entry %s: expected issues %v, got %v", path, expected[path], codes)
+		}
+	}
+
+	if report.Analysis.Total != 5 {
+		t.Errorf("Expected 5 analyzed pages, got %d", report.Analysis.Total)
+	}
+	if report.IssueCounts[IssueRobotsBlocked] != 1 || report.IssueCounts[IssueNon200] != 2 {
+		t.Errorf("Unexpected issue counts %v", report.IssueCounts)
+	}
+}
+
+// Test a document that is not a sitemap fails the check
+func TestRunNotASitemap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0"?><rss></rss>`)
+	}))
+	defer server.Close()
+
+	config := analyzer.DefaultConfig()
+	config.Network.AllowedNetworks = []string{"127.0.0.0/8"}
+	pageAnalyzer := analyzer.NewDefaultPageAnalyzer(&config, nil)
+	checker := NewChecker(DefaultConfig(), pageAnalyzer, pageAnalyzer, 2)
+	_, err := checker.Run(context.Background(), server.URL+"/feed.xml")
+	if err == nil {
+		t.Fatal("Expected an error for a document that is not a sitemap")
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"home24/internal/auth"
+)
+
+// MemoryStore keeps records in memory. It is meant for tests and for
+// deployments that don't need the history to survive a restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]*Record
+	keys    map[string]*auth.StoredKey
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]*Record),
+		keys:    make(map[string]*auth.StoredKey),
+	}
+}
+
+// Save implements ResultStore
+func (s *MemoryStore) Save(ctx context.Context, record *Record) error {
+	if err := prepare(record); err != nil {
+		return err
+	}
+	stored := *record
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = &stored
+	return nil
+}
+
+// Get implements ResultStore
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *record
+	return &found, nil
+}
+
+// List implements ResultStore
+func (s *MemoryStore) List(ctx context.Context, filter Filter) ([]Summary, error) {
+	s.mu.RLock()
+	var matches []*Record
+	for _, record := range s.records {
+		if filter.matches(record) {
+			matches = append(matches, record)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+
+	summaries := []Summary{}
+	for i := filter.offset(); i < len(matches) && len(summaries) < filter.limit(); i++ {
+		summaries = append(summaries, matches[i].Summary())
+	}
+	return summaries, nil
+}
+
+// SaveKey implements auth.KeyStore
+func (s *MemoryStore) SaveKey(ctx context.Context, key *auth.StoredKey) error {
+	if err := prepareKey(key); err != nil {
+		return err
+	}
+	stored := *key
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.keys {
+		if existing.Name == key.Name || existing.Hash == key.Hash {
+			return fmt.Errorf("error saving API key %q: the name or key is already in use", key.Name)
+		}
+	}
+	s.keys[key.ID] = &stored
+	return nil
+}
+
+// FindKey implements auth.KeyStore
+func (s *MemoryStore) FindKey(ctx context.Context, hash string) (*auth.StoredKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, auth.ErrKeyNotFound
+}
+
+// ListKeys implements auth.KeyStore
+func (s *MemoryStore) ListKeys(ctx context.Context) ([]auth.StoredKey, error) {
+	s.mu.RLock()
+	keys := []auth.StoredKey{}
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// DeleteKey implements auth.KeyStore
+func (s *MemoryStore) DeleteKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[id]; !ok {
+		return auth.ErrKeyNotFound
+	}
+	delete(s.keys, id)
+	return nil
+}
+
+// Ping implements ResultStore
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close implements ResultStore
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+)
+
+// How long saving a result may take once the analysis has finished
+const saveTimeout = 5 * time.Second
+
+// Recorder is a PageAnalyzer that saves every successful analysis to a
+// result store, along with the partial results of analyses that were
+// stopped while checking links
+type Recorder struct {
+	analyzer analyzer.PageAnalyzer
+	store    ResultStore
+	config   func() analyzer.AnalyzerConfig
+	log      *slog.Logger
+}
+
+// NewRecorder wraps an analyzer so its results are saved to the store. The
+// config function returns the analyzer settings recorded with each result.
+func NewRecorder(a analyzer.PageAnalyzer, s ResultStore, config func() analyzer.AnalyzerConfig, log *slog.Logger) *Recorder {
+	return &Recorder{
+		analyzer: a,
+		store:    s,
+		config:   config,
+		log:      log,
+	}
+}
+
+// Analyze implements PageAnalyzer. Failing to save a result is logged but
+// doesn't fail the analysis.
+func (r *Recorder) Analyze(ctx context.Context, urlStr string) (*analyzer.AnalysisResult, error) {
+	record, err := r.Record(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return record.Result, nil
+}
+
+// Record analyzes the page and returns the record saved for it. If saving
+// fails the error is logged and the record has no ID. The partial result of
+// a stopped analysis is saved too, but its error is returned. The settings
+// are recorded as they were when the analysis started, so a reload while it
+// runs doesn't change them.
+func (r *Recorder) Record(ctx context.Context, urlStr string) (*Record, error) {
+	config := NewConfigSnapshot(r.config())
+	result, err := r.analyzer.Analyze(ctx, urlStr)
+	if err != nil {
+		var analysisErr *analyzer.AnalysisError
+		if errors.As(err, &analysisErr) && analysisErr.Partial != nil {
+			r.save(ctx, urlStr, config, analysisErr.Partial)
+		}
+		return nil, err
+	}
+	return r.save(ctx, urlStr, config, result), nil
+}
+
+// save saves a result with the settings it was analyzed with and returns
+// its record
+func (r *Recorder) save(ctx context.Context, urlStr string, config ConfigSnapshot, result *analyzer.AnalysisResult) *Record {
+	record := &Record{
+		URL:      urlStr,
+		Duration: result.Duration,
+		Config:   config,
+		Result:   result,
+	}
+	if u, parseErr := url.Parse(urlStr); parseErr == nil {
+		record.Host = u.Hostname()
+	}
+	if identity := auth.FromContext(ctx); identity != nil {
+		record.APIKey = identity.Name
+	}
+
+	// The result is saved even if the caller has gone away in the meantime
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), saveTimeout)
+	defer cancel()
+	if saveErr := r.store.Save(saveCtx, record); saveErr != nil {
+		r.log.ErrorContext(ctx, "error saving analysis to history",
+			slog.String("url", urlStr),
+			slog.String("error", saveErr.Error()))
+		record.ID = ""
+	}
+	return record
+}
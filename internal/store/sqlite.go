@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+
+	// Registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// The schema of the history database
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS analyses (
+	id           TEXT PRIMARY KEY,
+	url          TEXT NOT NULL,
+	host         TEXT NOT NULL,
+	created_at   INTEGER NOT NULL,
+	duration_ns  INTEGER NOT NULL,
+	title        TEXT NOT NULL,
+	status_code  INTEGER NOT NULL,
+	total_links  INTEGER NOT NULL,
+	broken_links INTEGER NOT NULL,
+	config       TEXT NOT NULL,
+	result       TEXT NOT NULL,
+	api_key      TEXT NOT NULL DEFAULT '',
+	partial      INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS analyses_created_at ON analyses (created_at);
+CREATE INDEX IF NOT EXISTS analyses_host_created_at ON analyses (host, created_at);
+CREATE TABLE IF NOT EXISTS api_keys (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL UNIQUE,
+	hash       TEXT NOT NULL UNIQUE,
+	scopes     TEXT NOT NULL,
+	created_at INTEGER NOT NULL
+);
+`
+
+// The columns added to the analyses table after it was first created, with
+// their definitions
+var sqliteAddedColumns = []struct{ name, definition string }{
+	{"api_key", "TEXT NOT NULL DEFAULT ''"},
+	{"partial", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// SQLiteStore keeps records in an embedded SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens the database at path, creating the file, its
+// directory and the schema as needed
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating history directory: %w", err)
+		}
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening history database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating history schema: %w", err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating history schema: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// migrateSQLite adds the columns missing from databases created by older
+// versions
+func migrateSQLite(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('analyses')`)
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range sqliteAddedColumns {
+		if columns[column.name] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE analyses ADD COLUMN ` + column.name + ` ` + column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save implements ResultStore
+func (s *SQLiteStore) Save(ctx context.Context, record *Record) error {
+	if err := prepare(record); err != nil {
+		return err
+	}
+	config, err := json.Marshal(record.Config)
+	if err != nil {
+		return err
+	}
+	result, err := json.Marshal(record.Result)
+	if err != nil {
+		return err
+	}
+
+	summary := record.Summary()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO analyses (id, url, host, created_at, duration_ns, title, status_code, total_links, broken_links, config, result, api_key, partial)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, record.URL, record.Host, record.CreatedAt.UnixNano(), int64(record.Duration),
+		summary.Title, summary.StatusCode, summary.TotalLinks, summary.BrokenLinks,
+		string(config), string(result), record.APIKey, summary.Partial,
+	)
+	if err != nil {
+		return fmt.Errorf("error saving analysis: %w", err)
+	}
+	return nil
+}
+
+// Get implements ResultStore
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*Record, error) {
+	var record Record
+	var createdAt, duration int64
+	var config, result string
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, url, host, created_at, duration_ns, config, result, api_key
+		FROM analyses WHERE id = ?`, id,
+	).Scan(&record.ID, &record.URL, &record.Host, &createdAt, &duration, &config, &result, &record.APIKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading analysis: %w", err)
+	}
+
+	record.CreatedAt = time.Unix(0, createdAt).UTC()
+	record.Duration = time.Duration(duration)
+	if err := json.Unmarshal([]byte(config), &record.Config); err != nil {
+		return nil, fmt.Errorf("error decoding config snapshot: %w", err)
+	}
+	record.Result = &analyzer.AnalysisResult{}
+	if err := json.Unmarshal([]byte(result), record.Result); err != nil {
+		return nil, fmt.Errorf("error decoding analysis result: %w", err)
+	}
+	return &record, nil
+}
+
+// List implements ResultStore
+func (s *SQLiteStore) List(ctx context.Context, filter Filter) ([]Summary, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Host != "" {
+		conditions = append(conditions, "host = ?")
+		args = append(args, strings.ToLower(filter.Host))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UnixNano())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UnixNano())
+	}
+
+	query := `SELECT id, url, host, created_at, duration_ns, title, status_code, total_links, broken_links, api_key, partial FROM analyses`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.limit(), filter.offset())
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing analyses: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []Summary{}
+	for rows.Next() {
+		var summary Summary
+		var createdAt, duration int64
+		err := rows.Scan(&summary.ID, &summary.URL, &summary.Host, &createdAt, &duration,
+			&summary.Title, &summary.StatusCode, &summary.TotalLinks, &summary.BrokenLinks, &summary.APIKey, &summary.Partial)
+		if err != nil {
+			return nil, fmt.Errorf("error reading analysis: %w", err)
+		}
+		summary.CreatedAt = time.Unix(0, createdAt).UTC()
+		summary.Duration = time.Duration(duration)
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// SaveKey implements auth.KeyStore
+func (s *SQLiteStore) SaveKey(ctx context.Context, key *auth.StoredKey) error {
+	if err := prepareKey(key); err != nil {
+		return err
+	}
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, name, hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Hash, string(scopes), key.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving API key: %w", err)
+	}
+	return nil
+}
+
+// FindKey implements auth.KeyStore
+func (s *SQLiteStore) FindKey(ctx context.Context, hash string) (*auth.StoredKey, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, name, hash, scopes, created_at FROM api_keys WHERE hash = ?`, hash)
+	key, err := scanKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading API key: %w", err)
+	}
+	return key, nil
+}
+
+// ListKeys implements auth.KeyStore
+func (s *SQLiteStore) ListKeys(ctx context.Context) ([]auth.StoredKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, hash, scopes, created_at FROM api_keys ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []auth.StoredKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteKey implements auth.KeyStore
+func (s *SQLiteStore) DeleteKey(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting API key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return auth.ErrKeyNotFound
+	}
+	return nil
+}
+
+// scanKey reads an API key from a row
+func scanKey(row interface{ Scan(...interface{}) error }) (*auth.StoredKey, error) {
+	var key auth.StoredKey
+	var scopes string
+	var createdAt int64
+	if err := row.Scan(&key.ID, &key.Name, &key.Hash, &scopes, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &key, nil
+}
+
+// Ping implements ResultStore
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close implements ResultStore
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+)
+
+// ErrNotFound is returned for records that don't exist
+var ErrNotFound = errors.New("analysis not found")
+
+// The storage drivers
+const (
+	DriverSQLite = "sqlite"
+	DriverMemory = "memory"
+)
+
+// Config holds the result store settings
+type Config struct {
+	Driver string `yaml:"driver"`
+	Path   string `yaml:"path"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Driver: DriverSQLite,
+		Path:   "data/history.db",
+	}
+}
+
+// Open creates the store selected by the configuration
+func Open(config Config) (ResultStore, error) {
+	switch config.Driver {
+	case DriverSQLite, "":
+		return NewSQLiteStore(config.Path)
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown history driver %q", config.Driver)
+	}
+}
+
+// ResultStore keeps the history of analyses
+type ResultStore interface {
+	// Save stores a record and assigns its ID if it has none
+	Save(ctx context.Context, record *Record) error
+
+	// Get returns a record or ErrNotFound
+	Get(ctx context.Context, id string) (*Record, error)
+
+	// List returns the summaries of the matching records, newest first
+	List(ctx context.Context, filter Filter) ([]Summary, error)
+
+	// Ping checks that the store can be reached
+	Ping(ctx context.Context) error
+
+	// Close releases the resources of the store
+	Close() error
+}
+
+// ConfigSnapshot records the analyzer settings an analysis ran with. Network
+// options and credentials are left out as they may contain secrets.
+type ConfigSnapshot struct {
+	Timeout            time.Duration `json:"timeout_ns"`
+	MaxConcurrentLinks int           `json:"max_concurrent_links"`
+	UserAgent          string        `json:"user_agent"`
+	RetryAttempts      int           `json:"retry_attempts"`
+	MaxLinksPerPage    int           `json:"max_links_per_page"`
+	MaxDepth           int           `json:"max_depth"`
+}
+
+// NewConfigSnapshot takes a snapshot of the analyzer configuration
+func NewConfigSnapshot(config analyzer.AnalyzerConfig) ConfigSnapshot {
+	return ConfigSnapshot{
+		Timeout:            config.Timeout,
+		MaxConcurrentLinks: config.MaxConcurrentLinks,
+		UserAgent:          config.UserAgent,
+		RetryAttempts:      config.RetryAttempts,
+		MaxLinksPerPage:    config.MaxLinksPerPage,
+		MaxDepth:           config.MaxDepth,
+	}
+}
+
+// Record is a stored analysis
+type Record struct {
+	ID        string                   `json:"id"`
+	URL       string                   `json:"url"`
+	Host      string                   `json:"host"`
+	CreatedAt time.Time                `json:"created_at"`
+	Duration  time.Duration            `json:"duration_ns"`
+	Config    ConfigSnapshot           `json:"config"`
+	Result    *analyzer.AnalysisResult `json:"result"`
+
+	// APIKey is the name of the key the analysis was requested with
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// Summary describes a stored analysis in listings
+type Summary struct {
+	ID          string        `json:"id"`
+	URL         string        `json:"url"`
+	Host        string        `json:"host"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Duration    time.Duration `json:"duration_ns"`
+	Title       string        `json:"title"`
+	StatusCode  int           `json:"status_code"`
+	TotalLinks  int           `json:"total_links"`
+	BrokenLinks int           `json:"broken_links"`
+	APIKey      string        `json:"api_key,omitempty"`
+	Partial     bool          `json:"partial,omitempty"`
+}
+
+// Summary returns the summary of the record
+func (r *Record) Summary() Summary {
+	summary := Summary{
+		ID:        r.ID,
+		URL:       r.URL,
+		Host:      r.Host,
+		CreatedAt: r.CreatedAt,
+		Duration:  r.Duration,
+		APIKey:    r.APIKey,
+	}
+	if r.Result != nil {
+		summary.Title = r.Result.Title
+		summary.StatusCode = r.Result.StatusCode
+		summary.TotalLinks = len(r.Result.Links)
+		summary.BrokenLinks = len(r.Result.Links) - r.Result.AccessibleLinks
+		summary.Partial = r.Result.Partial
+	}
+	return summary
+}
+
+// The number of records listed when the filter sets no limit
+const defaultListLimit = 50
+
+// Filter selects records to list. Zero values match everything.
+type Filter struct {
+	Host   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// limit returns the page size to use
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return defaultListLimit
+	}
+	return f.Limit
+}
+
+// offset returns the number of records to skip
+func (f Filter) offset() int {
+	if f.Offset < 0 {
+		return 0
+	}
+	return f.Offset
+}
+
+// matches reports whether a record passes the filter
+func (f Filter) matches(r *Record) bool {
+	if f.Host != "" && !strings.EqualFold(r.Host, f.Host) {
+		return false
+	}
+	if !f.From.IsZero() && r.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// prepare fills in the ID and creation time of a new record
+func prepare(r *Record) error {
+	if r.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		r.ID = id
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	r.CreatedAt = r.CreatedAt.UTC()
+	r.Host = strings.ToLower(r.Host)
+	return nil
+}
+
+// prepareKey fills in the ID and creation time of a new API key
+func prepareKey(k *auth.StoredKey) error {
+	if k.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		k.ID = id
+	}
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+	k.CreatedAt = k.CreatedAt.UTC()
+	return nil
+}
+
+// newID returns a new random ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
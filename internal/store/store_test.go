@@ -0,0 +1,293 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/auth"
+)
+
+// testStores returns a fresh instance of every store implementation
+func testStores(t *testing.T) map[string]ResultStore {
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "nested", "history.db"))
+	if err != nil {
+		t.Fatalf("Error opening SQLite store: %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+
+	return map[string]ResultStore{
+		"memory": NewMemoryStore(),
+		"sqlite": sqlite,
+	}
+}
+
+// newRecord creates a record for a page on host analyzed at the given time
+func newRecord(host string, createdAt time.Time) *Record {
+	return &Record{
+		URL:       "https://" + host + "/",
+		Host:      host,
+		CreatedAt: createdAt,
+		Duration:  1500 * time.Millisecond,
+		Config:    ConfigSnapshot{Timeout: 10 * time.Second, RetryAttempts: 3, UserAgent: "test"},
+		Result: &analyzer.AnalysisResult{
+			URL:             "https://" + host + "/",
+			StatusCode:      200,
+			Title:           "Home of " + host,
+			Headings:        map[string]int{"h1": 1},
+			Links:           []analyzer.LinkInfo{{URL: "https://" + host + "/a", Accessible: true}, {URL: "https://" + host + "/b"}},
+			AccessibleLinks: 1,
+			HTMLVersion:     "HTML 5",
+		},
+	}
+}
+
+// Test records can be saved, reopened and listed with filters by every store
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			saved := newRecord("WWW.Example.com", day.Add(2*time.Hour))
+			if err := s.Save(ctx, saved); err != nil {
+				t.Fatalf("Error saving record: %v", err)
+			}
+			if saved.ID == "" || saved.Host != "www.example.com" {
+				t.Fatalf("Expected an ID and a lowercase host, got %+v", saved)
+			}
+			stopped := newRecord("shop.example.com", day.Add(3*time.Hour))
+			stopped.Result.Partial = true
+			for _, r := range []*Record{
+				newRecord("www.example.com", day.Add(26*time.Hour)),
+				stopped,
+			} {
+				if err := s.Save(ctx, r); err != nil {
+					t.Fatalf("Error saving record: %v", err)
+				}
+			}
+
+			record, err := s.Get(ctx, saved.ID)
+			if err != nil {
+				t.Fatalf("Error loading record: %v", err)
+			}
+			if !record.CreatedAt.Equal(saved.CreatedAt) || record.Duration != saved.Duration ||
+				record.Config != saved.Config || record.Result.Title != "Home of WWW.Example.com" ||
+				len(record.Result.Links) != 2 || record.Result.Headings["h1"] != 1 {
+				t.Errorf("Unexpected record %+v", record)
+			}
+
+			if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+
+			tests := []struct {
+				name   string
+				filter Filter
+				hosts  []string
+			}{
+				{"all newest first", Filter{}, []string{"www.example.com", "shop.example.com", "www.example.com"}},
+				{"by host", Filter{Host: "WWW.EXAMPLE.COM"}, []string{"www.example.com", "www.example.com"}},
+				{"by day", Filter{From: day, To: day.AddDate(0, 0, 1)}, []string{"shop.example.com", "www.example.com"}},
+				{"paged", Filter{Limit: 1, Offset: 1}, []string{"shop.example.com"}},
+				{"no match", Filter{Host: "other.example.com"}, []string{}},
+			}
+			for _, tt := range tests {
+				summaries, err := s.List(ctx, tt.filter)
+				if err != nil {
+					t.Fatalf("%s: error listing records: %v", tt.name, err)
+				}
+				hosts := []string{}
+				for _, summary := range summaries {
+					hosts = append(hosts, summary.Host)
+				}
+				if len(hosts) != len(tt.hosts) {
+					t.Errorf("%s: expected %v, got %v", tt.name, tt.hosts, hosts)
+					continue
+				}
+				for i := range hosts {
+					if hosts[i] != tt.hosts[i] {
+						t.Errorf("%s: expected %v, got %v", tt.name, tt.hosts, hosts)
+						break
+					}
+				}
+			}
+
+			summaries, _ := s.List(ctx, Filter{Host: "shop.example.com"})
+			if len(summaries) == 1 && (summaries[0].TotalLinks != 2 || summaries[0].BrokenLinks != 1 || summaries[0].StatusCode != 200 || !summaries[0].Partial) {
+				t.Errorf("Unexpected summary %+v", summaries[0])
+			}
+		})
+	}
+}
+
+// Test the SQLite history survives reopening the database
+func TestSQLiteStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Error opening SQLite store: %v", err)
+	}
+	record := newRecord("www.example.com", time.Now())
+	if err := s.Save(context.Background(), record); err != nil {
+		t.Fatalf("Error saving record: %v", err)
+	}
+	s.Close()
+
+	s, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Error reopening SQLite store: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Get(context.Background(), record.ID); err != nil {
+		t.Errorf("Expected the record after reopening, got %v", err)
+	}
+}
+
+// Test databases created before analyses were attributed to API keys get
+// the new column
+func TestSQLiteStoreMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE analyses (
+		id TEXT PRIMARY KEY, url TEXT NOT NULL, host TEXT NOT NULL, created_at INTEGER NOT NULL,
+		duration_ns INTEGER NOT NULL, title TEXT NOT NULL, status_code INTEGER NOT NULL,
+		total_links INTEGER NOT NULL, broken_links INTEGER NOT NULL, config TEXT NOT NULL, result TEXT NOT NULL)`)
+	if err != nil {
+		t.Fatalf("Error creating old schema: %v", err)
+	}
+	db.Exec(`INSERT INTO analyses VALUES ('old', 'https://example.com/', 'example.com', 1, 1, 'Old', 200, 0, 0, '{}', '{}')`)
+	db.Close()
+
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Error opening old database: %v", err)
+	}
+	defer s.Close()
+	if record, err := s.Get(context.Background(), "old"); err != nil || record.APIKey != "" {
+		t.Errorf("Expected the old record without a key, got %+v, %v", record, err)
+	}
+	record := newRecord("example.com", time.Now())
+	record.APIKey = "ci"
+	if err := s.Save(context.Background(), record); err != nil {
+		t.Fatalf("Error saving record: %v", err)
+	}
+	summaries, _ := s.List(context.Background(), Filter{})
+	if len(summaries) != 2 || summaries[0].APIKey != "ci" {
+		t.Errorf("Expected the new record to be attributed, got %+v", summaries)
+	}
+}
+
+// Test API keys can be saved, found by hash, listed and deleted by every store
+func TestKeyStores(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			keys := s.(auth.KeyStore)
+			first := &auth.StoredKey{Name: "ci", Hash: auth.HashKey("one"), Scopes: []string{auth.ScopeAnalyze}}
+			if err := keys.SaveKey(ctx, first); err != nil {
+				t.Fatalf("Error saving key: %v", err)
+			}
+			second := &auth.StoredKey{Name: "reports", Hash: auth.HashKey("two"), Scopes: []string{auth.ScopeReadHistory}, CreatedAt: first.CreatedAt.Add(time.Second)}
+			if err := keys.SaveKey(ctx, second); err != nil {
+				t.Fatalf("Error saving key: %v", err)
+			}
+			if err := keys.SaveKey(ctx, &auth.StoredKey{Name: "ci", Hash: auth.HashKey("three"), Scopes: []string{auth.ScopeAdmin}}); err == nil {
+				t.Error("Expected a second key with the same name to be rejected")
+			}
+
+			found, err := keys.FindKey(ctx, auth.HashKey("two"))
+			if err != nil || found.ID != second.ID || found.Name != "reports" || found.Scopes[0] != auth.ScopeReadHistory {
+				t.Errorf("Unexpected key %+v, %v", found, err)
+			}
+			if _, err := keys.FindKey(ctx, auth.HashKey("unknown")); !errors.Is(err, auth.ErrKeyNotFound) {
+				t.Errorf("Expected ErrKeyNotFound, got %v", err)
+			}
+
+			if err := keys.DeleteKey(ctx, first.ID); err != nil {
+				t.Fatalf("Error deleting key: %v", err)
+			}
+			if err := keys.DeleteKey(ctx, first.ID); !errors.Is(err, auth.ErrKeyNotFound) {
+				t.Errorf("Expected ErrKeyNotFound, got %v", err)
+			}
+			listed, _ := keys.ListKeys(ctx)
+			if len(listed) != 1 || listed[0].Name != "reports" {
+				t.Errorf("Expected only the remaining key, got %+v", listed)
+			}
+		})
+	}
+}
+
+// fakeAnalyzer returns a fixed result or error, calling during first if set
+type fakeAnalyzer struct {
+	result *analyzer.AnalysisResult
+	err    error
+	during func()
+}
+
+func (f *fakeAnalyzer) Analyze(ctx context.Context, urlStr string) (*analyzer.AnalysisResult, error) {
+	if f.during != nil {
+		f.during()
+	}
+	return f.result, f.err
+}
+
+// Test the recorder saves successful analyses with the config snapshot
+func TestRecorder(t *testing.T) {
+	s := NewMemoryStore()
+	config := analyzer.DefaultConfig()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	result := newRecord("www.example.com", time.Now()).Result
+	result.Duration = 2 * time.Second
+
+	recorder := NewRecorder(&fakeAnalyzer{result: result}, s, func() analyzer.AnalyzerConfig { return config }, log)
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{Name: "ci"})
+	if _, err := recorder.Analyze(ctx, "https://WWW.example.com/page"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	failing := NewRecorder(&fakeAnalyzer{err: errors.New("boom")}, s, func() analyzer.AnalyzerConfig { return config }, log)
+	if _, err := failing.Analyze(context.Background(), "https://www.example.com/"); err == nil {
+		t.Fatal("Expected the analyzer error")
+	}
+
+	summaries, _ := s.List(context.Background(), Filter{})
+	if len(summaries) != 1 {
+		t.Fatalf("Expected only the successful analysis, got %d records", len(summaries))
+	}
+	record, _ := s.Get(context.Background(), summaries[0].ID)
+	if record.Host != "www.example.com" || record.Duration != 2*time.Second || record.Config != NewConfigSnapshot(config) || record.APIKey != "ci" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Analyses stopped while checking links are saved with what they found
+	partial := newRecord("shop.example.com", time.Now()).Result
+	partial.Partial = true
+	stopped := NewRecorder(&fakeAnalyzer{err: &analyzer.AnalysisError{Code: analyzer.ErrCanceled, Message: "analysis stopped", Partial: partial}}, s, func() analyzer.AnalyzerConfig { return config }, log)
+	if _, err := stopped.Analyze(context.Background(), "https://shop.example.com/"); err == nil {
+		t.Fatal("Expected the analyzer error")
+	}
+	summaries, _ = s.List(context.Background(), Filter{Host: "shop.example.com"})
+	if len(summaries) != 1 || !summaries[0].Partial {
+		t.Errorf("Expected the partial result to be saved, got %+v", summaries)
+	}
+
+	// A reload while the analysis runs doesn't change the recorded settings
+	started := config
+	reloading := NewRecorder(&fakeAnalyzer{result: result, during: func() { config.UserAgent = "Reloaded/1.0" }}, s, func() analyzer.AnalyzerConfig { return config }, log)
+	saved, err := reloading.Record(context.Background(), "https://reload.example.com/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if saved.Config != NewConfigSnapshot(started) {
+		t.Errorf("Expected the settings the analysis started with, got %+v", saved.Config)
+	}
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// The OTLP exporters
+const (
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+)
+
+// Config holds the tracing settings
+type Config struct {
+	Enabled     bool   `yaml:"enabled"`
+	ServiceName string `yaml:"serviceName"`
+
+	// Exporter is otlp-grpc or otlp-http. Endpoint is the host and port of
+	// the collector, e.g. localhost:4317 for gRPC or localhost:4318 for HTTP.
+	Exporter string            `yaml:"exporter"`
+	Endpoint string            `yaml:"endpoint"`
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+
+	// The share of new traces that are sampled. Traces started by a caller
+	// follow the caller's sampling decision.
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		ServiceName: "webpage-analyzer",
+		Exporter:    ExporterOTLPGRPC,
+		Endpoint:    "localhost:4317",
+		SampleRatio: 1,
+	}
+}
+
+// Setup installs the W3C trace context propagator and, if tracing is
+// enabled, a global tracer provider exporting to the configured collector.
+// The returned function flushes the buffered spans and stops exporting.
+func Setup(ctx context.Context, config Config, serviceVersion string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+		semconv.ServiceVersion(serviceVersion),
+	))
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the configured OTLP exporter. Settings left empty
+// fall back to the standard OTEL_EXPORTER_OTLP_* environment variables.
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterOTLPGRPC:
+		var options []otlptracegrpc.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		if len(config.Headers) > 0 {
+			options = append(options, otlptracegrpc.WithHeaders(config.Headers))
+		}
+		return otlptracegrpc.New(ctx, options...)
+	case ExporterOTLPHTTP:
+		var options []otlptracehttp.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		if len(config.Headers) > 0 {
+			options = append(options, otlptracehttp.WithHeaders(config.Headers))
+		}
+		return otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+}
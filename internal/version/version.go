@@ -0,0 +1,57 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build details, stamped at build time with
+//
+//	-ldflags "-X home24/internal/version.Version=v1.2.3 -X home24/internal/version.Commit=abc1234 -X home24/internal/version.BuildDate=2024-05-01T12:00:00Z"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build details. Values that weren't stamped are taken from
+// the module and VCS information Go embeds in the binary where possible.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	return info
+}
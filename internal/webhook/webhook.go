@@ -0,0 +1,389 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/jobs"
+	"home24/internal/metrics"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Endpoint is a URL notified when a job finishes. Deliveries are signed
+// with the secret. Configured endpoints without one fall back to the global
+// secret; endpoints given with a job must bring their own.
+type Endpoint struct {
+	URL    string `yaml:"url" json:"url"`
+	Secret string `yaml:"secret" json:"secret,omitempty"`
+
+	// requested is set for endpoints given with a job rather than
+	// configured, which may only be reached at public addresses
+	requested bool
+}
+
+// Validate checks the endpoint URL
+func (e Endpoint) Validate() error {
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL %q must be an absolute http or https URL", e.URL)
+	}
+	return nil
+}
+
+// Config holds the webhook settings
+type Config struct {
+	Endpoints      []Endpoint    `yaml:"endpoints"`
+	Secret         string        `yaml:"secret"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	Timeout        time.Duration `yaml:"timeout"`
+	DeadLetterFile string        `yaml:"deadLetterFile"`
+
+	// AllowedNetworks are CIDR ranges or addresses that endpoints given
+	// with a job may be at although they are private, loopback or
+	// link-local. Configured endpoints may be anywhere.
+	AllowedNetworks []string `yaml:"allowedNetworks"`
+}
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Timeout:        10 * time.Second,
+	}
+}
+
+// Validate checks the configured endpoints
+func (c Config) Validate() error {
+	var errs []error
+	for _, endpoint := range c.Endpoints {
+		if err := endpoint.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	Event      string      `json:"event"`
+	DeliveryID string      `json:"delivery_id"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Job        jobs.Job    `json:"job"`
+	Result     interface{} `json:"result,omitempty"`
+}
+
+// deadLetter is a line of the dead-letter log
+type deadLetter struct {
+	Time     time.Time       `json:"time"`
+	URL      string          `json:"url"`
+	Event    string          `json:"event"`
+	Delivery string          `json:"delivery_id"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Notifier delivers job completions to webhook endpoints
+type Notifier struct {
+	config  Config
+	client  *http.Client
+	metrics *metrics.Metrics
+	log     *slog.Logger
+
+	// guard checks the endpoints given with jobs, which are delivered to
+	// with the guarded client
+	guard   *analyzer.Guard
+	guarded *http.Client
+
+	deadLetterMu sync.Mutex
+}
+
+// NewNotifier creates a new notifier recording deliveries to the given
+// metrics, which may be nil. Unset settings fall back to the defaults.
+func NewNotifier(config Config, m *metrics.Metrics, log *slog.Logger) *Notifier {
+	if m == nil {
+		m = metrics.New(nil, "")
+	}
+	defaults := DefaultConfig()
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	guard, err := analyzer.NewGuard(config.AllowedNetworks)
+	if err != nil {
+		log.Error("invalid webhook allowed networks, allowing none", slog.String("error", err.Error()))
+		guard, _ = analyzer.NewGuard(nil)
+	}
+
+	return &Notifier{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		metrics: m,
+		log:     log,
+		guard:   guard,
+		guarded: guard.Client(config.Timeout),
+	}
+}
+
+// CheckEndpoint checks an endpoint given with a job: its URL must be valid
+// and must not resolve to a blocked address, and it needs its own secret.
+// The address is checked again for every delivery.
+func (n *Notifier) CheckEndpoint(ctx context.Context, endpoint Endpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	if endpoint.Secret == "" {
+		return fmt.Errorf("webhook %q needs a secret", endpoint.URL)
+	}
+	u, _ := url.Parse(endpoint.URL)
+	return n.guard.CheckURL(ctx, u)
+}
+
+// Hook returns a job hook that notifies the configured endpoints and the
+// extra ones given for a single job. It returns nil if there is nobody to
+// notify.
+func (n *Notifier) Hook(extra []Endpoint) jobs.FinishHook {
+	endpoints := append([]Endpoint{}, n.config.Endpoints...)
+	for _, endpoint := range extra {
+		endpoint.requested = true
+		endpoints = append(endpoints, endpoint)
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, job jobs.Job) {
+		n.Notify(ctx, job, endpoints)
+	}
+}
+
+// Notify delivers the finished job to the endpoints and waits until every
+// delivery has succeeded or ended up in the dead-letter log. Deliveries that
+// are still pending when the context is done go to the dead-letter log
+// straight away.
+func (n *Notifier) Notify(ctx context.Context, job jobs.Job, endpoints []Endpoint) {
+	deliveryID, err := newDeliveryID()
+	if err != nil {
+		n.log.Error("error creating webhook delivery ID", slog.String("error", err.Error()))
+		return
+	}
+
+	payload := Payload{
+		Event:      "job." + string(job.Status),
+		DeliveryID: deliveryID,
+		Timestamp:  time.Now().UTC(),
+		Job:        job,
+		Result:     job.Result,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		n.log.Error("error encoding webhook payload", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(endpoint Endpoint) {
+			defer wg.Done()
+			n.deliver(ctx, endpoint, payload, body)
+		}(endpoint)
+	}
+	wg.Wait()
+}
+
+// deliver posts the payload to one endpoint, retrying with exponential
+// backoff, and writes it to the dead-letter log once all attempts failed or
+// the context is done
+func (n *Notifier) deliver(ctx context.Context, endpoint Endpoint, payload Payload, body []byte) {
+	backoff := n.config.InitialBackoff
+	var err error
+	var retry bool
+	attempt := 1
+
+	for ; attempt <= n.config.MaxAttempts; attempt++ {
+		retry, err = n.post(ctx, endpoint, payload, body)
+		if err == nil {
+			n.metrics.WebhookDeliveries.WithLabelValues("success").Inc()
+			n.log.Info("webhook delivered",
+				slog.String("url", endpoint.URL),
+				slog.String("delivery_id", payload.DeliveryID),
+				slog.Int("attempt", attempt),
+			)
+			return
+		}
+
+		n.metrics.WebhookDeliveries.WithLabelValues("error").Inc()
+		n.log.Warn("webhook delivery failed",
+			slog.String("url", endpoint.URL),
+			slog.String("delivery_id", payload.DeliveryID),
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+		if !retry || attempt == n.config.MaxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			err = fmt.Errorf("stopped retrying: %w", ctx.Err())
+			break
+		}
+		backoff *= 2
+		if backoff > n.config.MaxBackoff {
+			backoff = n.config.MaxBackoff
+		}
+	}
+
+	n.writeDeadLetter(endpoint, payload, body, attempt, err)
+}
+
+// post makes a single delivery attempt. The boolean reports whether the
+// failure is worth retrying.
+func (n *Notifier) post(ctx context.Context, endpoint Endpoint, payload Payload, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WebPageAnalyzer-Webhook/1.0")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.DeliveryID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+
+	// Endpoints given with a job are never signed with the global secret,
+	// or their owners could forge deliveries to the configured endpoints
+	secret := endpoint.Secret
+	if secret == "" && !endpoint.requested {
+		secret = n.config.Secret
+	}
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	}
+
+	client := n.client
+	if endpoint.requested {
+		client = n.guarded
+	}
+	resp, err := client.Do(req)
+	var blocked *analyzer.BlockedAddressError
+	if errors.As(err, &blocked) {
+		return false, err
+	}
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+// writeDeadLetter records a delivery that was given up on. The entry always
+// goes to the log and, if configured, is appended to the dead-letter file.
+func (n *Notifier) writeDeadLetter(endpoint Endpoint, payload Payload, body []byte, attempts int, cause error) {
+	n.metrics.WebhookDeadLetters.Inc()
+
+	entry := deadLetter{
+		Time:     time.Now().UTC(),
+		URL:      endpoint.URL,
+		Event:    payload.Event,
+		Delivery: payload.DeliveryID,
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Payload:  body,
+	}
+	n.log.Error("webhook delivery given up",
+		slog.String("url", entry.URL),
+		slog.String("delivery_id", entry.Delivery),
+		slog.String("job_id", payload.Job.ID),
+		slog.String("error", entry.Error),
+	)
+
+	if n.config.DeadLetterFile == "" {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	n.deadLetterMu.Lock()
+	defer n.deadLetterMu.Unlock()
+	file, err := os.OpenFile(n.config.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		n.log.Error("error opening webhook dead-letter file", slog.String("error", err.Error()))
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		n.log.Error("error writing webhook dead-letter file", slog.String("error", err.Error()))
+	}
+}
+
+// Sign returns the signature header value for a delivery: the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID generates a random delivery ID
+func newDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"home24/internal/analyzer"
+	"home24/internal/jobs"
+)
+
+// newTestNotifier creates a notifier with short backoffs
+func newTestNotifier(config Config) *Notifier {
+	config.InitialBackoff = time.Millisecond
+	config.MaxBackoff = 5 * time.Millisecond
+	return NewNotifier(config, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// Test deliveries are signed and retried until the endpoint accepts them
+func TestNotifyRetriesAndSigns(t *testing.T) {
+	var attempts atomic.Int32
+	var payload Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderSignature) != Sign("request-secret", r.Header.Get(HeaderTimestamp), body) {
+			t.Errorf("Unexpected signature %q", r.Header.Get(HeaderSignature))
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(Config{Secret: "global-secret", AllowedNetworks: []string{"127.0.0.0/8"}})
+	job := jobs.Job{ID: "job-1", Kind: "analysis", Status: jobs.StatusSucceeded}
+	notifier.Hook([]Endpoint{{URL: server.URL, Secret: "request-secret"}})(context.Background(), job)
+
+	if attempts.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts.Load())
+	}
+	if payload.Event != "job.succeeded" || payload.Job.ID != "job-1" || payload.DeliveryID == "" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+// Test failed deliveries end up in the dead-letter file. Client errors are
+// not retried.
+func TestNotifyDeadLetter(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	deadLetterFile := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	notifier := newTestNotifier(Config{
+		Endpoints:      []Endpoint{{URL: server.URL}},
+		DeadLetterFile: deadLetterFile,
+	})
+	notifier.Notify(context.Background(), jobs.Job{ID: "job-2", Status: jobs.StatusFailed}, notifier.config.Endpoints)
+
+	if attempts.Load() != 1 {
+		t.Errorf("Expected a single attempt for a client error, got %d", attempts.Load())
+	}
+
+	data, err := os.ReadFile(deadLetterFile)
+	if err != nil {
+		t.Fatalf("Error reading dead-letter file: %v", err)
+	}
+	var entry deadLetter
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Error decoding dead-letter entry %q: %v", data, err)
+	}
+	if entry.URL != server.URL || entry.Event != "job.failed" || entry.Attempts != 1 {
+		t.Errorf("Unexpected dead-letter entry %+v", entry)
+	}
+}
+
+// Test there is no hook without endpoints
+func TestHookWithoutEndpoints(t *testing.T) {
+	if newTestNotifier(Config{}).Hook(nil) != nil {
+		t.Error("Expected no hook without endpoints")
+	}
+}
+
+// Test endpoints given with a job can't reach internal addresses, directly
+// or through a redirect, while configured endpoints can
+func TestRequestedEndpointsAreGuarded(t *testing.T) {
+	var internal atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internal.Add(1)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(Config{Endpoints: []Endpoint{{URL: server.URL}}})
+	var blocked *analyzer.BlockedAddressError
+	if err := notifier.CheckEndpoint(context.Background(), Endpoint{URL: server.URL, Secret: "s"}); !errors.As(err, &blocked) {
+		t.Errorf("Expected a BlockedAddressError for a loopback endpoint, got %v", err)
+	}
+	if err := notifier.CheckEndpoint(context.Background(), Endpoint{URL: "http://169.254.169.254/latest", Secret: "s"}); !errors.As(err, &blocked) {
+		t.Errorf("Expected a BlockedAddressError for the metadata address, got %v", err)
+	}
+
+	notifier.Hook([]Endpoint{{URL: server.URL, Secret: "s"}})(context.Background(), jobs.Job{ID: "job-3", Status: jobs.StatusSucceeded})
+	if internal.Load() != 1 {
+		t.Errorf("Expected only the configured endpoint to be notified, got %d deliveries", internal.Load())
+	}
+
+	// An allowed endpoint that redirects to a blocked address isn't followed
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://[::1]:1/", http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+	notifier = newTestNotifier(Config{AllowedNetworks: []string{"127.0.0.0/8"}})
+	if retry, err := notifier.post(context.Background(), Endpoint{URL: redirect.URL, requested: true}, Payload{}, nil); retry || !errors.As(err, &blocked) {
+		t.Errorf("Expected the redirect to be refused without retrying, got %v, %v", retry, err)
+	}
+}
+
+// Test endpoints given with a job need their own secret and are never
+// signed with the global one
+func TestRequestedEndpointsNeedSecret(t *testing.T) {
+	var signature atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature.Store(r.Header.Get(HeaderSignature))
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(Config{Secret: "global-secret", AllowedNetworks: []string{"127.0.0.0/8"}})
+	if err := notifier.CheckEndpoint(context.Background(), Endpoint{URL: server.URL}); err == nil {
+		t.Error("Expected an endpoint without a secret to be rejected")
+	}
+
+	// Endpoints resumed from before secrets were required go out unsigned
+	notifier.Hook([]Endpoint{{URL: server.URL}})(context.Background(), jobs.Job{ID: "job-4", Status: jobs.StatusSucceeded})
+	if got := signature.Load(); got != "" {
+		t.Errorf("Expected no signature, got %q", got)
+	}
+
+	notifier = newTestNotifier(Config{Secret: "global-secret", Endpoints: []Endpoint{{URL: server.URL}}})
+	notifier.Notify(context.Background(), jobs.Job{ID: "job-5", Status: jobs.StatusSucceeded}, notifier.config.Endpoints)
+	if got := signature.Load(); got == "" {
+		t.Error("Expected configured endpoints to be signed with the global secret")
+	}
+}
+
+// Test deliveries still waiting to be retried when the context is done go to
+// the dead-letter file without waiting for the backoff
+func TestNotifyStopsOnShutdown(t *testing.T) {
+	attempted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempted <- struct{}{}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	deadLetterFile := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	notifier := NewNotifier(Config{
+		Endpoints:      []Endpoint{{URL: server.URL}},
+		InitialBackoff: time.Hour,
+		DeadLetterFile: deadLetterFile,
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-attempted
+		cancel()
+	}()
+	done := make(chan struct{})
+	go func() {
+		notifier.Notify(ctx, jobs.Job{ID: "job-6", Status: jobs.StatusSucceeded}, notifier.config.Endpoints)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the delivery to stop once the context was canceled")
+	}
+
+	data, err := os.ReadFile(deadLetterFile)
+	if err != nil {
+		t.Fatalf("Error reading dead-letter file: %v", err)
+	}
+	var entry deadLetter
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Error decoding dead-letter entry %q: %v", data, err)
+	}
+	if entry.Attempts != 1 || entry.Delivery == "" {
+		t.Errorf("Unexpected dead-letter entry %+v", entry)
+	}
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// The context key of the log attributes
+type attrsKey struct{}
+
+// This function returns a copy of the context whose log lines carry the
+// attributes, in addition to the ones already in the context. An attribute
+// replaces an earlier one with the same key.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	var current = Attrs(ctx)
+	var merged = make([]slog.Attr, 0, len(current)+len(attrs))
+	for _, attr := range current {
+		if !hasKey(attrs, attr.Key) {
+			merged = append(merged, attr)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// This function returns the log attributes carried by the context
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	var attrs, _ = ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// This function reports whether one of the attributes has the key
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextHandler adds the attributes of the context to every record logged
+// with one of the Context methods, e.g. InfoContext
+type ContextHandler struct {
+	slog.Handler
+}
+
+// This function wraps a handler so it logs the attributes of the context
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: handler}
+}
+
+// Handle implements slog.Handler
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
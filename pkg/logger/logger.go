@@ -1,24 +1,177 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 )
 
-// This function creates a new logger with good defaults
-func New() *slog.Logger {
-	// Create options for the handler
-	var options = &slog.HandlerOptions{
+// The log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// The outputs that aren't file paths
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// Config holds the logging settings
+type Config struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level"`
+
+	// Format is text or json
+	Format string `yaml:"format"`
+
+	// Outputs are stdout, stderr or file paths; every line goes to all of them
+	Outputs []string `yaml:"outputs"`
+
+	// AddSource adds the source file and line of the log call
+	AddSource bool `yaml:"addSource"`
+
+	// Sampling limits repeated debug lines
+	Sampling SamplingConfig `yaml:"sampling"`
+}
+
+// SamplingConfig limits how often the same debug message is logged. Of the
+// lines with the same message, the first Initial of each second are logged
+// and then every Thereafter-th. An Initial of 0 turns sampling off.
+type SamplingConfig struct {
+	Initial    int `yaml:"initial"`
+	Thereafter int `yaml:"thereafter"`
+}
+
+// This function returns a configuration with good defaults
+func DefaultConfig() Config {
+	return Config{
 		// Info is a good default level for most applications
-		Level: slog.LevelInfo,
+		Level:   "info",
+		Format:  FormatText,
+		Outputs: []string{OutputStdout},
+		Sampling: SamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+		},
+	}
+}
+
+// This function checks the settings and reports all problems
+func (c Config) Validate() error {
+	var errs []error
+	if _, err := ParseLevel(c.Level); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Format != FormatText && c.Format != FormatJSON {
+		errs = append(errs, fmt.Errorf("format must be %q or %q, got %q", FormatText, FormatJSON, c.Format))
+	}
+	if len(c.Outputs) == 0 {
+		errs = append(errs, errors.New("at least one output is required"))
+	}
+	if c.Sampling.Initial < 0 || c.Sampling.Thereafter < 0 {
+		errs = append(errs, errors.New("sampling values must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// This function parses a level name such as debug or WARN
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// This function returns the name of a level as used in the configuration
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// Logger is a configured logger whose level can be changed while it runs
+type Logger struct {
+	*slog.Logger
+
+	level *slog.LevelVar
+	files []*os.File
+}
+
+// This function creates a new logger from the configuration. Close must
+// be called to close the log files.
+func New(config Config) (*Logger, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	var level, _ = ParseLevel(config.Level)
+
+	var l = &Logger{level: new(slog.LevelVar)}
+	l.level.Set(level)
+
+	// Open the outputs
+	var writers []io.Writer
+	for _, output := range config.Outputs {
+		switch output {
+		case OutputStdout:
+			writers = append(writers, os.Stdout)
+		case OutputStderr:
+			writers = append(writers, os.Stderr)
+		default:
+			var file, err = os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				l.Close()
+				return nil, fmt.Errorf("error opening log file: %w", err)
+			}
+			l.files = append(l.files, file)
+			writers = append(writers, file)
+		}
+	}
+	var writer = writers[0]
+	if len(writers) > 1 {
+		writer = io.MultiWriter(writers...)
 	}
 
-	// Create a text handler for the logger that outputs to stdout
-	var handler = slog.NewTextHandler(os.Stdout, options)
+	// Create the handler for the format
+	var options = &slog.HandlerOptions{
+		Level:     l.level,
+		AddSource: config.AddSource,
+	}
+	var handler slog.Handler
+	if config.Format == FormatJSON {
+		handler = slog.NewJSONHandler(writer, options)
+	} else {
+		handler = slog.NewTextHandler(writer, options)
+	}
 
-	// Create a new logger with the handler
-	var logger = slog.New(handler)
+	// Sample the debug lines and add the attributes of the context, such as
+	// the request ID
+	if config.Sampling.Initial > 0 {
+		handler = newSamplingHandler(handler, config.Sampling)
+	}
+	l.Logger = slog.New(NewContextHandler(handler))
+	return l, nil
+}
 
-	// Return the logger
-	return logger
+// This function returns the current level
+func (l *Logger) Level() slog.Level {
+	return l.level.Level()
+}
+
+// This function changes the level of the logger and everything derived
+// from it
+func (l *Logger) SetLevel(level slog.Level) {
+	l.level.Set(level)
+}
+
+// This function closes the log files
+func (l *Logger) Close() error {
+	var errs []error
+	for _, file := range l.files {
+		errs = append(errs, file.Close())
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test lines logged with a context carry its attributes and later
+// attributes replace earlier ones with the same key
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	var log = slog.New(NewContextHandler(slog.NewTextHandler(&buf, nil)))
+
+	var ctx = WithAttrs(context.Background(), slog.String("request_id", "abc"), slog.String("target_url", "a"))
+	ctx = WithAttrs(ctx, slog.String("target_url", "b"), slog.String("job_id", "42"))
+	log.With("component", "test").InfoContext(ctx, "hello")
+
+	var line = buf.String()
+	for _, expected := range []string{"component=test", "request_id=abc", "target_url=b", "job_id=42"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Expected %q in %q", expected, line)
+		}
+	}
+	if strings.Contains(line, "target_url=a") {
+		t.Errorf("Expected the replaced attribute to be dropped from %q", line)
+	}
+
+	buf.Reset()
+	log.Info("no context")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("Expected no context attributes in %q", buf.String())
+	}
+}
+
+// Test valid request IDs are kept and others replaced
+func TestRequestID(t *testing.T) {
+	if id := RequestID("abc-123_x.y:z"); id != "abc-123_x.y:z" {
+		t.Errorf("Expected the given ID to be kept, got %q", id)
+	}
+	for _, given := range []string{"", "with space", "line\nbreak", strings.Repeat("a", 129)} {
+		id := RequestID(given)
+		if id == given || len(id) != 32 {
+			t.Errorf("Expected a new ID for %q, got %q", given, id)
+		}
+	}
+}
+
+// Test a JSON logger writes to its file and its level changes at runtime
+func TestNew(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "analyzer.log")
+	var config = DefaultConfig()
+	config.Format = FormatJSON
+	config.Outputs = []string{path}
+	config.AddSource = true
+
+	var l, err = New(config)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	var derived = l.With("component", "test")
+	derived.Debug("hidden")
+	l.SetLevel(slog.LevelDebug)
+	derived.Debug("shown")
+	if err := l.Close(); err != nil {
+		t.Fatalf("Error closing logger: %v", err)
+	}
+
+	var data, _ = os.ReadFile(path)
+	var lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line, got %q", lines)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q", lines[0])
+	}
+	if line["msg"] != "shown" || line["level"] != "DEBUG" || line["component"] != "test" || line["source"] == nil {
+		t.Errorf("Unexpected line %v", line)
+	}
+}
+
+// Test every problem of an invalid configuration is reported
+func TestValidate(t *testing.T) {
+	var config = Config{Level: "loud", Format: "xml", Sampling: SamplingConfig{Initial: -1}}
+	var err = config.Validate()
+	if err == nil {
+		t.Fatal("Expected the configuration to be invalid")
+	}
+	for _, expected := range []string{"log level", "format", "output", "sampling"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected a problem with the %s in %q", expected, err)
+		}
+	}
+}
+
+// Test repeated debug lines are sampled per second and message while other
+// levels are always logged
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	var handler = newSamplingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), SamplingConfig{Initial: 2, Thereafter: 3})
+	var log = slog.New(handler)
+
+	for i := 0; i < 10; i++ {
+		log.Debug("link check result")
+		log.Info("analysis complete")
+	}
+	log.Debug("other message")
+
+	var output = buf.String()
+	// Lines 1, 2, 5 and 8 of 10, unless the second changed in between
+	if n := strings.Count(output, "link check result"); n < 4 || n > 6 {
+		t.Errorf("Expected about 4 sampled debug lines, got %d", n)
+	}
+	if n := strings.Count(output, "analysis complete"); n != 10 {
+		t.Errorf("Expected all 10 info lines, got %d", n)
+	}
+	if !strings.Contains(output, "other message") {
+		t.Error("Expected other messages to be counted separately")
+	}
+}
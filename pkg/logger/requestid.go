@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// RequestIDHeader is the header that carries the ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// The key of the request ID in log lines
+const RequestIDKey = "request_id"
+
+// Request IDs given by callers are kept if they are short and harmless to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// This function returns the request ID given by a caller if it is valid, or
+// a new random one otherwise
+func RequestID(given string) string {
+	if validRequestID.MatchString(given) {
+		return given
+	}
+	var buf = make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// samplingHandler drops repeated debug lines. The counts are shared with the
+// handlers derived from it, so a message is limited however it is logged.
+type samplingHandler struct {
+	slog.Handler
+	config SamplingConfig
+	state  *samplingState
+}
+
+// samplingState counts the debug lines of the current second by message
+type samplingState struct {
+	mu     sync.Mutex
+	second time.Time
+	counts map[string]int
+}
+
+// This function wraps a handler so it samples debug lines
+func newSamplingHandler(handler slog.Handler, config SamplingConfig) *samplingHandler {
+	return &samplingHandler{
+		Handler: handler,
+		config:  config,
+		state:   &samplingState{counts: make(map[string]int)},
+	}
+}
+
+// Handle implements slog.Handler
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level <= slog.LevelDebug && !h.state.allow(record.Message, record.Time, h.config) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), config: h.config, state: h.state}
+}
+
+// WithGroup implements slog.Handler
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), config: h.config, state: h.state}
+}
+
+// This function counts a line and reports whether it should be logged
+func (s *samplingState) allow(message string, at time.Time, config SamplingConfig) bool {
+	var second = at.Truncate(time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !second.Equal(s.second) {
+		s.second = second
+		clear(s.counts)
+	}
+	s.counts[message]++
+
+	var n = s.counts[message]
+	if n <= config.Initial {
+		return true
+	}
+	return config.Thereafter > 0 && (n-config.Initial)%config.Thereafter == 0
+}